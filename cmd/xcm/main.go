@@ -7,12 +7,19 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/skeeey/xcm-cli/pkg/cmd/certs"
 	"github.com/skeeey/xcm-cli/pkg/cmd/clusters"
 	"github.com/skeeey/xcm-cli/pkg/cmd/connect"
+	"github.com/skeeey/xcm-cli/pkg/cmd/contexts"
+	"github.com/skeeey/xcm-cli/pkg/cmd/disconnect"
+	"github.com/skeeey/xcm-cli/pkg/cmd/doctor"
 	"github.com/skeeey/xcm-cli/pkg/cmd/login"
 	"github.com/skeeey/xcm-cli/pkg/cmd/logout"
 	"github.com/skeeey/xcm-cli/pkg/cmd/relay"
+	"github.com/skeeey/xcm-cli/pkg/cmd/status"
+	"github.com/skeeey/xcm-cli/pkg/cmd/upgrade"
 	"github.com/skeeey/xcm-cli/pkg/cmd/version"
+	"github.com/skeeey/xcm-cli/pkg/configs"
 )
 
 var root = &cobra.Command{
@@ -35,12 +42,21 @@ func init() {
 	// by the 'pflag' package:
 	//pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	// Register the global flags:
+	configs.AddProfileFlag(root.PersistentFlags())
+
 	// Register the subcommands:
 	root.AddCommand(login.NewCmd())
 	root.AddCommand(logout.NewCmd())
 	root.AddCommand(connect.NewCmd())
+	root.AddCommand(disconnect.NewCmd())
 	root.AddCommand(relay.NewCmd())
+	root.AddCommand(status.NewCmd())
+	root.AddCommand(upgrade.NewCmd())
+	root.AddCommand(doctor.NewCmd())
 	root.AddCommand(clusters.NewCmd())
+	root.AddCommand(contexts.NewCmd())
+	root.AddCommand(certs.NewCmd())
 	root.AddCommand(version.NewCmd())
 }
 
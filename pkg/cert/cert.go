@@ -2,13 +2,21 @@ package cert
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	cryptorand "crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1" // #nosec G505 the subject key id is the sha1 of the public key, see RFC 5280 4.2.1.2
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
 	"fmt"
 	"math/big"
+	"net"
+	"strings"
 	"time"
 
 	"k8s.io/client-go/util/keyutil"
@@ -18,6 +26,85 @@ const duration365d = time.Hour * 24 * 365
 
 const certificateBlockType = "CERTIFICATE"
 
+// The types of the generated keys.
+const (
+	KeyTypeRSA     = "rsa"
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeEd25519 = "ed25519"
+)
+
+const (
+	// DefaultCAValidity is the default lifetime of a self-signed CA.
+	DefaultCAValidity = duration365d * 10
+
+	// DefaultValidity is the default lifetime of a certificate that is signed by a CA.
+	DefaultValidity = duration365d
+
+	// rsaKeyBits is the size of the generated RSA keys.
+	rsaKeyBits = 2048
+
+	// serialNumberBits is the size of the random serial numbers.
+	serialNumberBits = 128
+
+	// clockSkew is the time that the certificates are valid before they are generated, it avoids the
+	// flakes due to the clock skew.
+	clockSkew = time.Hour
+)
+
+// KeyTypes are the supported key types.
+var KeyTypes = []string{KeyTypeRSA, KeyTypeECDSA, KeyTypeEd25519}
+
+// Options are the options to generate the keys and the certificates, the zero value generates RSA
+// keys, a CA of 10 years and certificates of 1 year.
+type Options struct {
+	// KeyType is the type of the generated keys, one of rsa, ecdsa (P-256) and ed25519.
+	KeyType string
+
+	// CAValidity is the lifetime of the self-signed CAs.
+	CAValidity time.Duration
+
+	// Validity is the lifetime of the certificates that are signed by the CAs, a certificate doesn't
+	// outlive its CA.
+	Validity time.Duration
+}
+
+// Validate checks the key type and the lifetimes.
+func (o *Options) Validate() error {
+	switch o.keyType() {
+	case KeyTypeRSA, KeyTypeECDSA, KeyTypeEd25519:
+	default:
+		return fmt.Errorf("unsupported key type %q, the supported key types are %s",
+			o.KeyType, strings.Join(KeyTypes, ", "))
+	}
+
+	if o.CAValidity < 0 || o.Validity < 0 {
+		return fmt.Errorf("the validity of the certificates cannot be negative")
+	}
+
+	return nil
+}
+
+func (o *Options) keyType() string {
+	if o == nil || o.KeyType == "" {
+		return KeyTypeRSA
+	}
+	return o.KeyType
+}
+
+func (o *Options) caValidity() time.Duration {
+	if o == nil || o.CAValidity == 0 {
+		return DefaultCAValidity
+	}
+	return o.CAValidity
+}
+
+func (o *Options) validity() time.Duration {
+	if o == nil || o.Validity == 0 {
+		return DefaultValidity
+	}
+	return o.Validity
+}
+
 type APIServerCerts struct {
 	// service account key
 	ServiceAccountKey []byte
@@ -35,153 +122,398 @@ type APIServerCerts struct {
 	ServingCertKey []byte
 }
 
-func GenerateAPIServerCerts(apiHostName string) (*APIServerCerts, error) {
+// GenerateAPIServerCerts generates the CAs and the certificates of an API server, the host of the API
+// server is a subject alternative name of the serving certificate, its port is ignored. If the options
+// are nil, the default options are used.
+func GenerateAPIServerCerts(apiHostName string, opts *Options) (*APIServerCerts, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
 	apiServerCerts := &APIServerCerts{}
 
 	// service account key
-	serviceAccountKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+	serviceAccountKey, err := generateServiceAccountKey(opts)
 	if err != nil {
 		return nil, fmt.Errorf("service account key failed to generate: %v", err)
 	}
 
-	serviceAccountKeyBytes, err := generateKey(serviceAccountKey)
+	apiServerCerts.ServiceAccountKey = serviceAccountKey
+
+	// client certificates
+	clientCA, err := NewSelfSignedCA("xCMClientCA", opts)
+	if err != nil {
+		return nil, fmt.Errorf("self signed client ca failed to generate: %v", err)
+	}
+
+	// serving certificates
+	servingCA, err := NewSelfSignedCA("xCMServingCA", opts)
+	if err != nil {
+		return nil, fmt.Errorf("self signed serving ca failed to generate: %v", err)
+	}
+
+	if err := apiServerCerts.signCerts(clientCA, servingCA, apiHostName, opts); err != nil {
+		return nil, err
+	}
+
+	return apiServerCerts, nil
+}
+
+// GenerateAPIServerCertsWithCA generates the certificates of an API server that are signed by the
+// given CA, e.g. an intermediate CA of a corporate PKI, the CA is both the client CA and the serving CA.
+// If the options are nil, the default options are used, the CA validity is ignored.
+func GenerateAPIServerCertsWithCA(ca *CA, apiHostName string, opts *Options) (*APIServerCerts, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	apiServerCerts := &APIServerCerts{}
+
+	serviceAccountKey, err := generateServiceAccountKey(opts)
 	if err != nil {
 		return nil, fmt.Errorf("service account key failed to generate: %v", err)
 	}
+	apiServerCerts.ServiceAccountKey = serviceAccountKey
+
+	if err := apiServerCerts.signCerts(ca, ca, apiHostName, opts); err != nil {
+		return nil, err
+	}
 
-	apiServerCerts.ServiceAccountKey = serviceAccountKeyBytes
+	return apiServerCerts, nil
+}
 
-	// client certificates
-	clientCAKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+// signCerts sets the CAs and signs the admin client certificate and the serving certificate.
+func (c *APIServerCerts) signCerts(clientCA, servingCA *CA, apiHostName string, opts *Options) error {
+	var err error
+
+	c.ClientCA = clientCA.CertPEM()
+	c.ClientCAKey, err = clientCA.KeyPEM()
 	if err != nil {
-		return nil, fmt.Errorf("client ca key failed to generate: %v", err)
+		return fmt.Errorf("client ca key failed to encode: %v", err)
 	}
 
-	clientCA, clientCAKeyBytes, clinetCACert, err := newSelfSignedCACert("xCMClientCA", clientCAKey)
+	c.ClientCert, c.ClientCertKey, err = clientCA.NewCert(&CertConfig{
+		CommonName:    "system:admin",
+		Organizations: []string{"system:masters"},
+		Usages:        []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, opts)
 	if err != nil {
-		return nil, fmt.Errorf("self signed client ca failed to generate: %v", err)
+		return fmt.Errorf("client cert key failed to generate: %v", err)
 	}
 
-	apiServerCerts.ClientCA = clientCA
-	apiServerCerts.ClientCAKey = clientCAKeyBytes
+	c.ServingCA = servingCA.CertPEM()
+	c.ServingCAKey, err = servingCA.KeyPEM()
+	if err != nil {
+		return fmt.Errorf("serving ca key failed to encode: %v", err)
+	}
 
-	clientCert, clinetKey, err := generateSelfSignedCertKey(
-		clinetCACert, clientCAKey,
-		"system:admin", []string{"system:masters"},
-		[]string{},
-		x509.ExtKeyUsageClientAuth,
-	)
+	c.ServingCert, c.ServingCertKey, err = servingCA.NewCert(&CertConfig{
+		CommonName: "kubernetes.default",
+		Hosts:      []string{"kubernetes.default.svc", "localhost", apiHostName},
+		Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, opts)
 	if err != nil {
-		return nil, fmt.Errorf("self signed client cert key failed to generate: %v", err)
+		return fmt.Errorf("serving cert key failed to generate: %v", err)
 	}
 
-	apiServerCerts.ClientCert = clientCert
-	apiServerCerts.ClientCertKey = clinetKey
+	return nil
+}
+
+// CA is a certificate authority that signs the certificates.
+type CA struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+}
 
-	// serving certificates
-	servingCAKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+// CertConfig is the subject, the subject alternative names and the usages of a certificate.
+type CertConfig struct {
+	CommonName    string
+	Organizations []string
+
+	// Hosts are the DNS names and the IP addresses of the certificate, the port of a host is ignored.
+	Hosts []string
+
+	Usages []x509.ExtKeyUsage
+}
+
+// NewSelfSignedCA generates a key with the key type of the options and a self-signed CA with the
+// CA validity of the options.
+func NewSelfSignedCA(commonName string, opts *Options) (*CA, error) {
+	key, err := generatePrivateKey(opts.keyType())
 	if err != nil {
-		return nil, fmt.Errorf("serving ca key failed to generate: %v", err)
+		return nil, err
 	}
 
-	servingCA, servingCAKeyBytes, servingCACert, err := newSelfSignedCACert("xCMServingCA", servingCAKey)
+	serialNumber, err := newSerialNumber()
 	if err != nil {
-		return nil, fmt.Errorf("self signed serving ca failed to generate: %v", err)
+		return nil, err
 	}
 
-	apiServerCerts.ServingCA = servingCA
-	apiServerCerts.ServingCAKey = servingCAKeyBytes
+	subjectKeyID, err := newSubjectKeyID(key.Public())
+	if err != nil {
+		return nil, err
+	}
 
-	servingCert, servingKey, err := generateSelfSignedCertKey(
-		servingCACert, servingCAKey,
-		"kubernetes.default", []string{""},
-		[]string{
-			"kubernetes.default.svc",
-			"localhost",
-			apiHostName,
+	validFrom := time.Now().Add(-clockSkew).UTC()
+	tmpl := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName: commonName,
 		},
-		x509.ExtKeyUsageServerAuth,
-	)
+		NotBefore:             validFrom,
+		NotAfter:              validFrom.Add(opts.caValidity()),
+		KeyUsage:              keyUsage(key) | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SubjectKeyId:          subjectKeyID,
+	}
+
+	derBytes, err := x509.CreateCertificate(cryptorand.Reader, &tmpl, &tmpl, key.Public(), key)
 	if err != nil {
-		return nil, fmt.Errorf("self signed serving cert key failed to generate: %v", err)
+		return nil, err
 	}
 
-	apiServerCerts.ServingCert = servingCert
-	apiServerCerts.ServingCertKey = servingKey
+	caCert, err := x509.ParseCertificate(derBytes)
+	if err != nil {
+		return nil, err
+	}
 
-	return apiServerCerts, nil
+	return &CA{Cert: caCert, Key: key}, nil
 }
 
-func newSelfSignedCACert(commonName string, key *rsa.PrivateKey) ([]byte, []byte, *x509.Certificate, error) {
-	now := time.Now()
+// NewSubCA generates a key with the key type of the options and an intermediate CA that is signed by
+// the CA with the CA validity of the options, the intermediate CA doesn't outlive the CA and cannot
+// sign other CAs.
+func (ca *CA) NewSubCA(commonName string, opts *Options) (*CA, error) {
+	key, err := generatePrivateKey(opts.keyType())
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	subjectKeyID, err := newSubjectKeyID(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	validFrom := time.Now().Add(-clockSkew).UTC()
+	validTo := validFrom.Add(opts.caValidity())
+	if validTo.After(ca.Cert.NotAfter) {
+		validTo = ca.Cert.NotAfter
+	}
+
 	tmpl := x509.Certificate{
-		SerialNumber: new(big.Int).SetInt64(0),
+		SerialNumber: serialNumber,
 		Subject: pkix.Name{
 			CommonName: commonName,
 		},
-		NotBefore:             now.UTC(),
-		NotAfter:              now.Add(duration365d * 10).UTC(),
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		NotBefore:             validFrom,
+		NotAfter:              validTo,
+		KeyUsage:              keyUsage(key) | x509.KeyUsageCertSign,
 		BasicConstraintsValid: true,
 		IsCA:                  true,
+		MaxPathLenZero:        true,
+		SubjectKeyId:          subjectKeyID,
+		AuthorityKeyId:        ca.Cert.SubjectKeyId,
+	}
+
+	derBytes, err := x509.CreateCertificate(cryptorand.Reader, &tmpl, ca.Cert, key.Public(), ca.Key)
+	if err != nil {
+		return nil, err
 	}
 
-	clientCADERBytes, err := x509.CreateCertificate(cryptorand.Reader, &tmpl, &tmpl, key.Public(), key)
+	subCACert, err := x509.ParseCertificate(derBytes)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 
-	clinetCACert, err := x509.ParseCertificate(clientCADERBytes)
+	return &CA{Cert: subCACert, Key: key}, nil
+}
+
+// LoadCA returns the CA of the PEM encoded certificate and key, the certificate must be a CA and
+// the key must match the certificate.
+func LoadCA(certData, keyData []byte) (*CA, error) {
+	caCert, err := ParseCertificate(certData)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, fmt.Errorf("invalid CA certificate: %v", err)
+	}
+	if !caCert.IsCA {
+		return nil, fmt.Errorf("the certificate %q is not a CA", caCert.Subject.CommonName)
 	}
 
-	clientCA, clientCAKey, err := generateCertAndKey(clientCADERBytes, key)
+	key, err := keyutil.ParsePrivateKeyPEM(keyData)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, fmt.Errorf("failed to parse the CA key: %v", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported CA key %T", key)
+	}
+
+	publicKey, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(caCert.PublicKey) {
+		return nil, fmt.Errorf("the CA key doesn't match the CA certificate %q", caCert.Subject.CommonName)
 	}
 
-	return clientCA, clientCAKey, clinetCACert, nil
+	return &CA{Cert: caCert, Key: signer}, nil
 }
 
-func generateSelfSignedCertKey(caCertificate *x509.Certificate,
-	caKey *rsa.PrivateKey,
-	commonName string,
-	organizations, alternateDNS []string,
-	extKeyUsages ...x509.ExtKeyUsage) ([]byte, []byte, error) {
-	validFrom := time.Now().Add(-time.Hour) // valid an hour earlier to avoid flakes due to clock skew
-	maxAge := time.Hour * 24 * 365          // one year self-signed certs
+// CertPEM returns the PEM encoded certificate of the CA.
+func (ca *CA) CertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: certificateBlockType, Bytes: ca.Cert.Raw})
+}
+
+// KeyPEM returns the PEM encoded key of the CA.
+func (ca *CA) KeyPEM() ([]byte, error) {
+	return generateKey(ca.Key)
+}
 
-	priv, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+// NewCert generates a key with the key type of the options and a certificate that is signed by the
+// CA, it returns the PEM encoded certificate and key.
+func (ca *CA) NewCert(config *CertConfig, opts *Options) ([]byte, []byte, error) {
+	key, err := generatePrivateKey(opts.keyType())
 	if err != nil {
 		return nil, nil, err
 	}
 
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subjectKeyID, err := newSubjectKeyID(key.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	validFrom := time.Now().Add(-clockSkew).UTC()
+	validTo := validFrom.Add(opts.validity())
+	if validTo.After(ca.Cert.NotAfter) {
+		validTo = ca.Cert.NotAfter
+	}
+
 	template := x509.Certificate{
-		SerialNumber: big.NewInt(2),
+		SerialNumber: serialNumber,
 		Subject: pkix.Name{
-			CommonName:   commonName,
-			Organization: organizations,
+			CommonName:   config.CommonName,
+			Organization: config.Organizations,
 		},
 		NotBefore: validFrom,
-		NotAfter:  validFrom.Add(maxAge),
+		NotAfter:  validTo,
 
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage:           extKeyUsages,
+		KeyUsage:              keyUsage(key),
+		ExtKeyUsage:           config.Usages,
 		BasicConstraintsValid: true,
+		SubjectKeyId:          subjectKeyID,
+		AuthorityKeyId:        ca.Cert.SubjectKeyId,
 	}
 
-	template.DNSNames = append(template.DNSNames, alternateDNS...)
+	for _, host := range config.Hosts {
+		if host == "" {
+			continue
+		}
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+			continue
+		}
+		template.DNSNames = append(template.DNSNames, host)
+	}
 
-	derBytes, err := x509.CreateCertificate(cryptorand.Reader, &template, caCertificate, &priv.PublicKey, caKey)
+	derBytes, err := x509.CreateCertificate(cryptorand.Reader, &template, ca.Cert, key.Public(), ca.Key)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return generateCertAndKey(derBytes, priv)
+	return generateCertAndKey(derBytes, key)
+}
+
+// generateServiceAccountKey generates the PEM encoded key that signs the service account tokens, the
+// tokens cannot be signed with the ed25519 keys, an RSA key is generated instead.
+func generateServiceAccountKey(opts *Options) ([]byte, error) {
+	keyType := KeyTypeRSA
+	if opts.keyType() == KeyTypeECDSA {
+		keyType = KeyTypeECDSA
+	}
+
+	key, err := generatePrivateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	return generateKey(key)
+}
+
+// generatePrivateKey generates a key of the key type, the ECDSA keys are on the P-256 curve.
+func generatePrivateKey(keyType string) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeRSA:
+		return rsa.GenerateKey(cryptorand.Reader, rsaKeyBits)
+	case KeyTypeECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	case KeyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(cryptorand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", keyType)
+	}
+}
+
+// keyUsage returns the key usage of a key, only the RSA keys are used for the key encipherment.
+func keyUsage(key crypto.Signer) x509.KeyUsage {
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		return x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
+	}
+	return x509.KeyUsageDigitalSignature
+}
+
+// newSerialNumber returns a random positive serial number of 128 bits.
+func newSerialNumber() (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), serialNumberBits)
+	serialNumber, err := cryptorand.Int(cryptorand.Reader, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %v", err)
+	}
+
+	// a serial number must be positive
+	return serialNumber.Add(serialNumber, big.NewInt(1)), nil
+}
+
+// newSubjectKeyID returns the SHA-1 hash of the public key, see RFC 5280 4.2.1.2.
+func newSubjectKeyID(publicKey crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var spki struct {
+		Algorithm        pkix.AlgorithmIdentifier
+		SubjectPublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, err
+	}
+
+	// #nosec G401 the sha1 is not used for the security
+	sum := sha1.Sum(spki.SubjectPublicKey.Bytes)
+	return sum[:], nil
 }
 
-func generateCertAndKey(derBytes []byte, key *rsa.PrivateKey) ([]byte, []byte, error) {
+func generateCertAndKey(derBytes []byte, key crypto.Signer) ([]byte, []byte, error) {
 	// generate cert
 	certBuffer := bytes.Buffer{}
 	if err := pem.Encode(&certBuffer, &pem.Block{Type: certificateBlockType, Bytes: derBytes}); err != nil {
@@ -196,11 +528,31 @@ func generateCertAndKey(derBytes []byte, key *rsa.PrivateKey) ([]byte, []byte, e
 	return certBuffer.Bytes(), keyBytes, nil
 }
 
-func generateKey(key *rsa.PrivateKey) ([]byte, error) {
-	// generate key
+// generateKey encodes the key with PEM, the RSA keys are in PKCS #1, the ECDSA keys are in SEC 1 and
+// the ed25519 keys are in PKCS #8.
+func generateKey(key crypto.Signer) ([]byte, error) {
+	var block *pem.Block
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: keyutil.RSAPrivateKeyBlockType, Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: keyutil.ECPrivateKeyBlockType, Bytes: der}
+	case ed25519.PrivateKey:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: keyutil.PrivateKeyBlockType, Bytes: der}
+	default:
+		return nil, fmt.Errorf("unsupported key %T", key)
+	}
+
 	keyBuffer := bytes.Buffer{}
-	if err := pem.Encode(&keyBuffer, &pem.Block{
-		Type: keyutil.RSAPrivateKeyBlockType, Bytes: x509.MarshalPKCS1PrivateKey(key)}); err != nil {
+	if err := pem.Encode(&keyBuffer, block); err != nil {
 		return nil, err
 	}
 	return keyBuffer.Bytes(), nil
@@ -0,0 +1,321 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+)
+
+func mustParseCert(t *testing.T, data []byte) *x509.Certificate {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != certificateBlockType {
+		t.Fatalf("expected a PEM encoded certificate, but got %q", data)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return cert
+}
+
+func verify(t *testing.T, caData, certData []byte, usage x509.ExtKeyUsage, dnsName string) *x509.Certificate {
+	ca := mustParseCert(t, caData)
+	cert := mustParseCert(t, certData)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	if _, err := cert.Verify(x509.VerifyOptions{
+		DNSName:   dnsName,
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{usage},
+	}); err != nil {
+		t.Fatalf("failed to verify the certificate %s: %v", cert.Subject.CommonName, err)
+	}
+
+	if len(cert.SubjectKeyId) == 0 || !bytes.Equal(cert.AuthorityKeyId, ca.SubjectKeyId) {
+		t.Errorf("expected the key ids chain the certificate %s to its CA", cert.Subject.CommonName)
+	}
+	return cert
+}
+
+func TestGenerateAPIServerCerts(t *testing.T) {
+	cases := []struct {
+		keyType string
+		check   func(cert *x509.Certificate) bool
+	}{
+		{keyType: "", check: func(cert *x509.Certificate) bool {
+			_, ok := cert.PublicKey.(*rsa.PublicKey)
+			return ok && cert.KeyUsage&x509.KeyUsageKeyEncipherment != 0
+		}},
+		{keyType: KeyTypeECDSA, check: func(cert *x509.Certificate) bool {
+			key, ok := cert.PublicKey.(*ecdsa.PublicKey)
+			return ok && key.Curve.Params().Name == "P-256" && cert.KeyUsage&x509.KeyUsageKeyEncipherment == 0
+		}},
+		{keyType: KeyTypeEd25519, check: func(cert *x509.Certificate) bool {
+			_, ok := cert.PublicKey.(ed25519.PublicKey)
+			return ok
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.keyType, func(t *testing.T) {
+			certs, err := GenerateAPIServerCerts("cp.example.com:443", &Options{KeyType: c.keyType})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			serving := verify(t, certs.ServingCA, certs.ServingCert, x509.ExtKeyUsageServerAuth, "cp.example.com")
+			client := verify(t, certs.ClientCA, certs.ClientCert, x509.ExtKeyUsageClientAuth, "")
+			if client.Subject.CommonName != "system:admin" || client.Subject.Organization[0] != "system:masters" {
+				t.Errorf("unexpected subject of the client certificate %v", client.Subject)
+			}
+
+			for _, cert := range []*x509.Certificate{serving, client, mustParseCert(t, certs.ServingCA)} {
+				if !c.check(cert) {
+					t.Errorf("unexpected key of the certificate %s", cert.Subject.CommonName)
+				}
+			}
+
+			// the keys match the certificates
+			for _, pair := range [][2][]byte{
+				{certs.ServingCert, certs.ServingCertKey},
+				{certs.ClientCert, certs.ClientCertKey},
+				{certs.ServingCA, certs.ServingCAKey},
+				{certs.ClientCA, certs.ClientCAKey},
+			} {
+				if _, err := tls.X509KeyPair(pair[0], pair[1]); err != nil {
+					t.Errorf("the key doesn't match the certificate: %v", err)
+				}
+			}
+
+			if len(certs.ServiceAccountKey) == 0 {
+				t.Errorf("expected the service account key")
+			}
+		})
+	}
+}
+
+func TestSerialNumbers(t *testing.T) {
+	ca, err := NewSelfSignedCA("ca", &Options{KeyType: KeyTypeECDSA})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	serials := map[string]bool{ca.Cert.SerialNumber.String(): true}
+	for i := 0; i < 10; i++ {
+		certData, _, err := ca.NewCert(&CertConfig{CommonName: "client"}, &Options{KeyType: KeyTypeECDSA})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		serial := mustParseCert(t, certData).SerialNumber
+		if serial.Sign() <= 0 || serial.BitLen() > serialNumberBits+1 {
+			t.Errorf("unexpected serial number %s", serial)
+		}
+		if serials[serial.String()] {
+			t.Errorf("the serial number %s is reused", serial)
+		}
+		serials[serial.String()] = true
+	}
+}
+
+func TestNewCert(t *testing.T) {
+	opts := &Options{KeyType: KeyTypeECDSA, CAValidity: 48 * time.Hour, Validity: 72 * time.Hour}
+	ca, err := NewSelfSignedCA("ca", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lifetime := ca.Cert.NotAfter.Sub(ca.Cert.NotBefore); lifetime != 48*time.Hour {
+		t.Errorf("expected the CA is valid for 48h, but got %s", lifetime)
+	}
+
+	certData, _, err := ca.NewCert(&CertConfig{
+		CommonName: "kubernetes.default",
+		Hosts:      []string{"localhost", "10.0.0.1:30443", "", "2001:db8::1"},
+		Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cert := verify(t, ca.CertPEM(), certData, x509.ExtKeyUsageServerAuth, "10.0.0.1")
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "localhost" {
+		t.Errorf("unexpected DNS names %v", cert.DNSNames)
+	}
+	if len(cert.IPAddresses) != 2 || !cert.IPAddresses[0].Equal(net.ParseIP("10.0.0.1")) ||
+		!cert.IPAddresses[1].Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("unexpected IP addresses %v", cert.IPAddresses)
+	}
+	// the certificate doesn't outlive its CA
+	if !cert.NotAfter.Equal(ca.Cert.NotAfter) {
+		t.Errorf("expected the certificate expires with its CA at %s, but got %s", ca.Cert.NotAfter, cert.NotAfter)
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	for _, opts := range []*Options{{KeyType: "dsa"}, {Validity: -time.Hour}} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expected error with %v", opts)
+		}
+	}
+
+	if _, err := GenerateAPIServerCerts("localhost", &Options{KeyType: "dsa"}); err == nil {
+		t.Errorf("expected error with an unsupported key type")
+	}
+}
+
+// newIntermediateCA returns the PEM encoded certificate and key of an intermediate CA that is signed by
+// the root CA.
+func newIntermediateCA(t *testing.T, root *CA) ([]byte, []byte) {
+	key, err := generatePrivateKey(KeyTypeECDSA)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, root.Cert, key.Public(), root.Key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	certData, keyData, err := generateCertAndKey(der, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return certData, keyData
+}
+
+func TestGenerateAPIServerCertsWithCA(t *testing.T) {
+	root, err := NewSelfSignedCA("root", &Options{KeyType: KeyTypeEd25519})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	caData, caKeyData := newIntermediateCA(t, root)
+
+	ca, err := LoadCA(caData, caKeyData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	certs, err := GenerateAPIServerCertsWithCA(ca, "10.0.0.1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(certs.ServingCA, caData) || !bytes.Equal(certs.ClientCA, caData) {
+		t.Errorf("expected the CA is both the serving CA and the client CA")
+	}
+
+	// the certificates chain to the root CA through the intermediate CA
+	roots := x509.NewCertPool()
+	roots.AddCert(root.Cert)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(ca.Cert)
+	for cert, usage := range map[*x509.Certificate]x509.ExtKeyUsage{
+		mustParseCert(t, certs.ServingCert): x509.ExtKeyUsageServerAuth,
+		mustParseCert(t, certs.ClientCert):  x509.ExtKeyUsageClientAuth,
+	} {
+		if _, err := cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{usage},
+		}); err != nil {
+			t.Errorf("failed to verify the certificate %s: %v", cert.Subject.CommonName, err)
+		}
+		// the certificate doesn't outlive the intermediate CA
+		if cert.NotAfter.After(ca.Cert.NotAfter) {
+			t.Errorf("expected the certificate %s expires before its CA", cert.Subject.CommonName)
+		}
+	}
+}
+
+func TestNewSubCA(t *testing.T) {
+	root, err := NewSelfSignedCA("root", &Options{CAValidity: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	subCA, err := root.NewSubCA("sub", &Options{KeyType: KeyTypeECDSA})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := subCA.Cert.CheckSignatureFrom(root.Cert); err != nil {
+		t.Errorf("expected the sub CA is signed by the root CA: %v", err)
+	}
+	if !subCA.Cert.IsCA || subCA.Cert.MaxPathLen != 0 || !subCA.Cert.MaxPathLenZero {
+		t.Errorf("expected the sub CA cannot sign other CAs")
+	}
+	// the sub CA doesn't outlive the root CA
+	if subCA.Cert.NotAfter.After(root.Cert.NotAfter) {
+		t.Errorf("expected the sub CA expires before the root CA")
+	}
+
+	certData, _, err := subCA.NewCert(&CertConfig{
+		CommonName: "system:admin",
+		Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root.Cert)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(subCA.Cert)
+	if _, err := mustParseCert(t, certData).Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Errorf("failed to verify the certificate: %v", err)
+	}
+}
+
+func TestLoadCA(t *testing.T) {
+	ca, err := NewSelfSignedCA("ca", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyData, err := ca.KeyPEM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certData, leafKeyData, err := ca.NewCert(&CertConfig{CommonName: "leaf"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := LoadCA(ca.CertPEM(), keyData); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := LoadCA(certData, leafKeyData); err == nil {
+		t.Errorf("expected error with a certificate that is not a CA")
+	}
+	if _, err := LoadCA(ca.CertPEM(), leafKeyData); err == nil {
+		t.Errorf("expected error with a key that doesn't match the CA")
+	}
+	if _, err := LoadCA(keyData, keyData); err == nil {
+		t.Errorf("expected error with a key as the certificate")
+	}
+}
@@ -0,0 +1,80 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+// DefaultWarningThreshold is the default remaining validity below which a certificate is reported as
+// expiring.
+const DefaultWarningThreshold = 30 * 24 * time.Hour
+
+// The statuses of an inspected certificate.
+const (
+	StatusValid    = "Valid"
+	StatusExpiring = "Expiring"
+	StatusExpired  = "Expired"
+)
+
+// CertificateInfo describes a certificate that is inspected.
+type CertificateInfo struct {
+	Name      string    `json:"name"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	SANs      []string  `json:"sans,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	Status    string    `json:"status"`
+}
+
+// Inspect returns the information of the certificate, it is expiring if its remaining validity is less
+// than the warning threshold.
+func Inspect(name string, cert *x509.Certificate, threshold time.Duration) *CertificateInfo {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	info := &CertificateInfo{
+		Name:      name,
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		SANs:      sans,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		Status:    StatusValid,
+	}
+
+	switch remaining := info.Remaining(); {
+	case remaining <= 0:
+		info.Status = StatusExpired
+	case remaining < threshold:
+		info.Status = StatusExpiring
+	}
+
+	return info
+}
+
+// Remaining returns the remaining validity of the certificate, it's negative once the certificate
+// expired.
+func (i *CertificateInfo) Remaining() time.Duration {
+	return time.Until(i.NotAfter)
+}
+
+// ParseCertificate parses the first certificate of the PEM encoded data, e.g. the leaf certificate of
+// a certificate bundle.
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != certificateBlockType {
+		return nil, fmt.Errorf("the data is not a PEM encoded certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the certificate: %v", err)
+	}
+
+	return cert, nil
+}
@@ -0,0 +1,52 @@
+package cert
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInspect(t *testing.T) {
+	ca, err := NewSelfSignedCA("ca", &Options{KeyType: KeyTypeECDSA, CAValidity: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	certData, _, err := ca.NewCert(&CertConfig{
+		CommonName:    "system:admin",
+		Organizations: []string{"system:masters"},
+		Hosts:         []string{"cp.example.com", "10.0.0.1"},
+	}, &Options{KeyType: KeyTypeECDSA})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	leaf, err := ParseCertificate(append(certData, ca.CertPEM()...))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info := Inspect("admin", leaf, time.Hour)
+	if info.Name != "admin" || info.Subject != "CN=system:admin,O=system:masters" || info.Issuer != "CN=ca" {
+		t.Errorf("unexpected certificate %v", info)
+	}
+	if len(info.SANs) != 2 || info.SANs[0] != "cp.example.com" || info.SANs[1] != "10.0.0.1" {
+		t.Errorf("unexpected SANs %v", info.SANs)
+	}
+	if info.Status != StatusValid {
+		t.Errorf("expected the certificate is valid, but got %s", info.Status)
+	}
+
+	// the certificate expires with its CA in 47 hours
+	if status := Inspect("admin", leaf, DefaultWarningThreshold).Status; status != StatusExpiring {
+		t.Errorf("expected the certificate is expiring, but got %s", status)
+	}
+
+	leaf.NotAfter = time.Now().Add(-time.Minute)
+	if status := Inspect("admin", leaf, time.Hour).Status; status != StatusExpired {
+		t.Errorf("expected the certificate is expired, but got %s", status)
+	}
+
+	if _, err := ParseCertificate([]byte("certificate")); err == nil {
+		t.Errorf("expected error with invalid data")
+	}
+}
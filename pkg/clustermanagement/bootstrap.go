@@ -0,0 +1,224 @@
+package clustermanagement
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/genericflags"
+	"github.com/skeeey/xcm-cli/pkg/managedcluster"
+	"github.com/skeeey/xcm-cli/pkg/resource"
+)
+
+const (
+	// DefaultBootstrapTokenTTL is the default lifetime of the bootstrap token of a relayed cluster.
+	DefaultBootstrapTokenTTL = 24 * time.Hour
+
+	// minBootstrapTokenTTL is the minimal lifetime of a service account token that the API server
+	// issues.
+	minBootstrapTokenTTL = 10 * time.Minute
+
+	// bootstrapTokenPlaceholder is the bootstrap token in the rendered manifests, the token is minted
+	// only when the cluster is relayed.
+	bootstrapTokenPlaceholder = "BOOTSTRAP_TOKEN"
+
+	// clusterNameLabel is the label of the certificate signing requests with the name of the cluster
+	// that requests them.
+	clusterNameLabel = "open-cluster-management.io/cluster-name"
+)
+
+const bootstrapNamespaceFile = "manifests/hub/bootstrap-namespace.yaml"
+
+var bootstrapFiles = []string{
+	"manifests/hub/bootstrap-serviceaccount.yaml",
+	"manifests/hub/bootstrap-clusterrole.yaml",
+	"manifests/hub/bootstrap-clusterrolebinding.yaml",
+}
+
+// bootstrapObjects returns the objects on the control plane that the cluster bootstraps with, the
+// service account is bound to a cluster role that can only request and watch the certificate signing
+// requests and update the managed cluster of the cluster.
+func (d *SpokeDeployer) bootstrapObjects() []runtime.Object {
+	return mustCreateObjects(append([]string{bootstrapNamespaceFile}, bootstrapFiles...), d.bootstrapConfig())
+}
+
+func (d *SpokeDeployer) bootstrapConfig() interface{} {
+	return struct {
+		Namespace      string
+		ServiceAccount string
+		ClusterName    string
+	}{
+		Namespace:      constants.DefaultBootstrapNamespace,
+		ServiceAccount: bootstrapServiceAccountName(d.clusterName),
+		ClusterName:    d.clusterName,
+	}
+}
+
+// ensureBootstrapKubeconfig creates the bootstrap service account of the cluster on the control plane
+// and builds the bootstrap kubeconfig with a token of the service account, the token expires after
+// the bootstrap token ttl.
+func (d *SpokeDeployer) ensureBootstrapKubeconfig(ctx context.Context) error {
+	if err := resource.ApplyResources(ctx, d.hubKubeClient, nil, nil, d.bootstrapObjects()...); err != nil {
+		return err
+	}
+
+	expirationSeconds := int64(d.bootstrapTokenTTL / time.Second)
+	tokenRequest, err := d.hubKubeClient.CoreV1().ServiceAccounts(constants.DefaultBootstrapNamespace).CreateToken(
+		ctx,
+		bootstrapServiceAccountName(d.clusterName),
+		&authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expirationSeconds},
+		},
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to request the bootstrap token: %v", err)
+	}
+
+	kubeconfig, err := buildBootstrapKubeconfig(d.hubConfig, tokenRequest.Status.Token)
+	if err != nil {
+		return err
+	}
+
+	d.bootstrapKubeconfig = kubeconfig
+	return nil
+}
+
+// removeBootstrapObjects removes the bootstrap service account of the cluster and its role from the
+// control plane, the namespace is shared by the clusters.
+func (d *SpokeDeployer) removeBootstrapObjects(ctx context.Context) error {
+	objects := mustCreateObjects(bootstrapFiles, d.bootstrapConfig())
+	return resource.DeleteResources(ctx, d.hubKubeClient, nil, nil, reverse(objects)...)
+}
+
+// waitForClusterConnected approves the certificate signing requests of the cluster until the managed
+// cluster is connected. The control plane only approves the renewals of the agent, the requests of
+// the bootstrap service account are approved by the relay.
+func (d *SpokeDeployer) waitForClusterConnected(ctx context.Context) error {
+	return wait.Poll(1*time.Second, genericflags.TimeOut(), func() (bool, error) {
+		if err := d.approveBootstrapCSRs(ctx); err != nil {
+			return false, err
+		}
+
+		cluster, err := d.hubClusterClient.ClusterV1().ManagedClusters().Get(ctx, d.clusterName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		return managedcluster.IsManagedClusterConnected(cluster), nil
+	})
+}
+
+// approveBootstrapCSRs approves the pending certificate signing requests of the cluster that are
+// requested by its bootstrap service account for a client certificate of its agent.
+func (d *SpokeDeployer) approveBootstrapCSRs(ctx context.Context) error {
+	csrs, err := d.hubKubeClient.CertificatesV1().CertificateSigningRequests().List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", clusterNameLabel, d.clusterName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the certificate signing requests of %s: %v", d.clusterName, err)
+	}
+
+	for i := range csrs.Items {
+		csr := &csrs.Items[i]
+		if !d.isBootstrapCSR(csr) {
+			continue
+		}
+
+		csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+			Type:           certificatesv1.CertificateApproved,
+			Status:         corev1.ConditionTrue,
+			Reason:         "XCMRelayApprove",
+			Message:        "Approved by the relay of the cluster",
+			LastUpdateTime: metav1.Now(),
+		})
+		if _, err := d.hubKubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(
+			ctx, csr.Name, csr, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to approve the certificate signing request %s: %v", csr.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// isBootstrapCSR returns true if the certificate signing request is pending, is requested by the
+// bootstrap service account of the cluster and asks for a client certificate of the agent of the
+// cluster.
+func (d *SpokeDeployer) isBootstrapCSR(csr *certificatesv1.CertificateSigningRequest) bool {
+	for _, condition := range csr.Status.Conditions {
+		if condition.Type == certificatesv1.CertificateApproved || condition.Type == certificatesv1.CertificateDenied {
+			return false
+		}
+	}
+
+	if csr.Spec.SignerName != certificatesv1.KubeAPIServerClientSignerName {
+		return false
+	}
+
+	user := fmt.Sprintf("system:serviceaccount:%s:%s", constants.DefaultBootstrapNamespace,
+		bootstrapServiceAccountName(d.clusterName))
+	if csr.Spec.Username != user {
+		return false
+	}
+
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil {
+		return false
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(request.Subject.CommonName,
+		fmt.Sprintf("system:open-cluster-management:%s:", d.clusterName))
+}
+
+// buildBootstrapKubeconfig returns a kubeconfig of the control plane that authenticates with the
+// token.
+func buildBootstrapKubeconfig(hubConfig *rest.Config, token string) ([]byte, error) {
+	caData := hubConfig.CAData
+	if len(caData) == 0 && hubConfig.CAFile != "" {
+		data, err := os.ReadFile(hubConfig.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA of the control plane: %v", err)
+		}
+		caData = data
+	}
+
+	return clientcmd.Write(clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{"default-cluster": {
+			Server:                   hubConfig.Host,
+			CertificateAuthorityData: caData,
+			InsecureSkipTLSVerify:    hubConfig.Insecure,
+			TLSServerName:            hubConfig.ServerName,
+		}},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{"default-auth": {
+			Token: token,
+		}},
+		Contexts: map[string]*clientcmdapi.Context{"default-context": {
+			Cluster:  "default-cluster",
+			AuthInfo: "default-auth",
+		}},
+		CurrentContext: "default-context",
+	})
+}
+
+func bootstrapServiceAccountName(clusterName string) string {
+	return fmt.Sprintf("%s-bootstrap", clusterName)
+}
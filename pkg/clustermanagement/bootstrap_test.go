@@ -0,0 +1,167 @@
+package clustermanagement
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"reflect"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+func TestEnsureBootstrapKubeconfig(t *testing.T) {
+	hubKubeClient := fake.NewSimpleClientset()
+	var expirationSeconds int64
+	hubKubeClient.PrependReactor("create", "serviceaccounts", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+
+		tokenRequest := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+		expirationSeconds = *tokenRequest.Spec.ExpirationSeconds
+		tokenRequest.Status.Token = "token1"
+		return true, tokenRequest, nil
+	})
+
+	d := &SpokeDeployer{
+		hubKubeClient:     hubKubeClient,
+		hubConfig:         &rest.Config{Host: "https://hub:443", TLSClientConfig: rest.TLSClientConfig{CAData: []byte("ca")}},
+		bootstrapTokenTTL: time.Hour,
+		clusterName:       "cluster-c1",
+	}
+	if err := d.ensureBootstrapKubeconfig(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := hubKubeClient.CoreV1().ServiceAccounts(constants.DefaultBootstrapNamespace).Get(
+		context.TODO(), "cluster-c1-bootstrap", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the bootstrap service account is created, but got %v", err)
+	}
+	role, err := hubKubeClient.RbacV1().ClusterRoles().Get(context.TODO(), "xcm:bootstrap:cluster-c1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the bootstrap cluster role is created, but got %v", err)
+	}
+	for _, rule := range role.Rules {
+		if rule.Resources[0] == "managedclusters" &&
+			(len(rule.ResourceNames) != 1 || rule.ResourceNames[0] != "cluster-c1") {
+			t.Errorf("expected the managed cluster is restricted, but got %v", role.Rules)
+		}
+		// the registration agent creates its request and watches it until it is approved
+		if rule.Resources[0] == "certificatesigningrequests" &&
+			!reflect.DeepEqual(rule.Verbs, []string{"create", "get", "list", "watch"}) {
+			t.Errorf("expected the certificate signing requests can be created and watched, but got %v", rule.Verbs)
+		}
+	}
+	if expirationSeconds != 3600 {
+		t.Errorf("expected the token expires in an hour, but got %d seconds", expirationSeconds)
+	}
+
+	config, err := clientcmd.Load(d.bootstrapKubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current := config.Contexts[config.CurrentContext]
+	if authInfo := config.AuthInfos[current.AuthInfo]; authInfo.Token != "token1" ||
+		len(authInfo.ClientCertificateData) != 0 {
+		t.Errorf("expected the kubeconfig authenticates with the token, but got %v", authInfo)
+	}
+	if cluster := config.Clusters[current.Cluster]; cluster.Server != "https://hub:443" ||
+		string(cluster.CertificateAuthorityData) != "ca" {
+		t.Errorf("unexpected cluster %v", cluster)
+	}
+
+	if err := d.removeBootstrapObjects(context.TODO()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateBootstrapTokenTTL(t *testing.T) {
+	for ttl, valid := range map[time.Duration]bool{0: true, time.Hour: true, time.Minute: false} {
+		err := (&SpokeDeployerOptions{BootstrapTokenTTL: ttl}).Validate()
+		if valid && err != nil {
+			t.Errorf("unexpected error with %s: %v", ttl, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("expected error with %s", ttl)
+		}
+	}
+}
+
+func TestApproveBootstrapCSRs(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newCSR := func(name, clusterName, username, commonName string,
+		conditions ...certificatesv1.CertificateSigningRequestCondition) *certificatesv1.CertificateSigningRequest {
+		request, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: commonName},
+		}, key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return &certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{clusterNameLabel: clusterName}},
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				Request:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: request}),
+				SignerName: certificatesv1.KubeAPIServerClientSignerName,
+				Username:   username,
+			},
+			Status: certificatesv1.CertificateSigningRequestStatus{Conditions: conditions},
+		}
+	}
+
+	const (
+		bootstrapUser = "system:serviceaccount:xcm-bootstrap:cluster-c1-bootstrap"
+		agentName     = "system:open-cluster-management:cluster-c1:agent1"
+	)
+	denied := certificatesv1.CertificateSigningRequestCondition{
+		Type: certificatesv1.CertificateDenied, Status: corev1.ConditionTrue,
+	}
+	hubKubeClient := fake.NewSimpleClientset(
+		newCSR("bootstrap", "cluster-c1", bootstrapUser, agentName),
+		newCSR("denied", "cluster-c1", bootstrapUser, agentName, denied),
+		newCSR("other-user", "cluster-c1", "system:serviceaccount:xcm-bootstrap:cluster-c2-bootstrap", agentName),
+		newCSR("other-cluster", "cluster-c1", bootstrapUser, "system:open-cluster-management:cluster-c2:agent1"),
+		newCSR("other-label", "cluster-c2", bootstrapUser, agentName),
+	)
+
+	d := &SpokeDeployer{hubKubeClient: hubKubeClient, clusterName: "cluster-c1"}
+	if err := d.approveBootstrapCSRs(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, expected := range map[string]bool{
+		"bootstrap": true, "denied": false, "other-user": false, "other-cluster": false, "other-label": false,
+	} {
+		csr, err := hubKubeClient.CertificatesV1().CertificateSigningRequests().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		approved := false
+		for _, condition := range csr.Status.Conditions {
+			if condition.Type == certificatesv1.CertificateApproved {
+				approved = true
+			}
+		}
+		if approved != expected {
+			t.Errorf("expected the request %s is approved %v, but got %v", name, expected, approved)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package clustermanagement
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/skeeey/xcm-cli/pkg/cert"
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+// controlPlaneCAName is the common name of the sub CA of the control plane.
+const controlPlaneCAName = "xCMControlPlaneCA"
+
+// CAOptions are the options of the CA that the certificates of the control plane are signed by, the
+// control plane generates its own CAs if no CA is given. The given CA signs a sub CA of the control
+// plane, only the key of the sub CA is stored on the cluster.
+type CAOptions struct {
+	// CACertFile and CAKeyFile are the local files of the PEM encoded CA certificate and key, e.g. an
+	// intermediate CA of a corporate PKI.
+	CACertFile string
+	CAKeyFile  string
+
+	// CASecret is a TLS secret on the hosting cluster that has the CA certificate and key, its format
+	// is '<namespace>/<name>'.
+	CASecret string
+}
+
+// AddCAFlags adds the flags of the CA of the control plane to the given set of command line flags.
+func AddCAFlags(flags *pflag.FlagSet, opts *CAOptions) {
+	flags.StringVar(
+		&opts.CACertFile,
+		"ca-cert",
+		"",
+		"The PEM encoded CA certificate that signs a sub CA of the control plane, the serving and the client "+
+			"certificates of the control plane are signed by the sub CA, and only the key of the sub CA is stored "+
+			"on the cluster. The control plane generates its own CAs by default, and a deployed control plane "+
+			"keeps the CA it was deployed with.",
+	)
+
+	flags.StringVar(
+		&opts.CAKeyFile,
+		"ca-key",
+		"",
+		"The PEM encoded key of the CA certificate, it is required with '--ca-cert'.",
+	)
+
+	flags.StringVar(
+		&opts.CASecret,
+		"ca-secret",
+		"",
+		"The TLS secret on the cluster that has the CA certificate and key, e.g. 'cert-manager/xcm-ca', the CA "+
+			"signs a sub CA of the control plane like '--ca-cert'. It cannot be used with '--ca-cert'.",
+	)
+}
+
+// Validate checks the CA files and the CA secret.
+func (o *CAOptions) Validate() error {
+	if (o.CACertFile == "") != (o.CAKeyFile == "") {
+		return fmt.Errorf("the CA certificate and the CA key must be specified together")
+	}
+
+	if o.CASecret == "" {
+		return nil
+	}
+
+	if o.CACertFile != "" {
+		return fmt.Errorf("the CA secret cannot be used with the CA certificate")
+	}
+
+	if _, _, err := o.caSecret(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// enabled returns true if a CA is given.
+func (o *CAOptions) enabled() bool {
+	return o.CACertFile != "" || o.CASecret != ""
+}
+
+// caSecret returns the namespace and the name of the CA secret.
+func (o *CAOptions) caSecret() (string, string, error) {
+	namespace, name, ok := strings.Cut(o.CASecret, "/")
+	if !ok || namespace == "" || name == "" {
+		return "", "", fmt.Errorf("the CA secret %q is not in the format '<namespace>/<name>'", o.CASecret)
+	}
+	return namespace, name, nil
+}
+
+// loadCA loads the CA from the local files or the CA secret on the cluster, it returns nil if no CA is
+// given.
+func (o *CAOptions) loadCA(ctx context.Context, kubeClient kubernetes.Interface) (*cert.CA, error) {
+	if o.CACertFile != "" {
+		certData, err := os.ReadFile(o.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA certificate: %v", err)
+		}
+
+		keyData, err := os.ReadFile(o.CAKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA key: %v", err)
+		}
+
+		return cert.LoadCA(certData, keyData)
+	}
+
+	if o.CASecret == "" {
+		return nil, nil
+	}
+
+	namespace, name, err := o.caSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the CA secret %s: %v", o.CASecret, err)
+	}
+
+	ca, err := cert.LoadCA(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("the CA secret %s is invalid: %v", o.CASecret, err)
+	}
+
+	return ca, nil
+}
+
+// loadCA loads the CA that the certificates of the control plane are signed by, it's a sub CA that is
+// signed by the given CA, so that the key of the given CA is never stored on the cluster. The sub CA in
+// the config secret of the control plane is reused if it's signed by the given CA, a control plane
+// that is deployed with another CA cannot be switched to the given CA, since it doesn't trust the
+// certificates that are signed by the new sub CA.
+func (d *controlPlaneDeployer) loadCA(ctx context.Context) error {
+	parent, err := d.caOpts.loadCA(ctx, d.kubeClient)
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		return nil
+	}
+
+	secret, err := d.kubeClient.CoreV1().Secrets(d.config.Namespace).Get(
+		ctx, controlPlaneConfigSecretName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get the config secret of the control plane: %v", err)
+	}
+	if err == nil && len(secret.Data[apiServerCAKeyKey]) > 0 {
+		ca, err := cert.LoadCA(secret.Data[apiServerCAKey], secret.Data[apiServerCAKeyKey])
+		if err == nil && ca.Cert.CheckSignatureFrom(parent.Cert) == nil {
+			d.ca = ca
+			return nil
+		}
+	}
+
+	_, err = d.kubeClient.AppsV1().Deployments(d.config.Namespace).Get(ctx, constants.ControlPlaneName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return fmt.Errorf("the control plane %s/%s is not signed by the given CA %q, disconnect the cluster "+
+			"and connect it again to switch the CA", d.config.Namespace, constants.ControlPlaneName,
+			parent.Cert.Subject.CommonName)
+	case !errors.IsNotFound(err):
+		return fmt.Errorf("failed to get the control plane: %v", err)
+	}
+
+	ca, err := parent.NewSubCA(controlPlaneCAName, nil)
+	if err != nil {
+		return fmt.Errorf("failed to sign the CA of the control plane: %v", err)
+	}
+
+	d.ca = ca
+	return nil
+}
+
+// checkCA checks if the CA can be loaded.
+func (d *controlPlaneDeployer) checkCA(ctx context.Context) ComponentStatus {
+	status := ComponentStatus{Name: "CA"}
+	ca, err := d.caOpts.loadCA(ctx, d.kubeClient)
+	if err != nil {
+		status.Reason = err.Error()
+		return status
+	}
+
+	status.Healthy = true
+	status.Reason = fmt.Sprintf("the certificates are signed by a sub CA of %q", ca.Cert.Subject.CommonName)
+	return status
+}
+
+// generateCerts generates the certificates of the control plane that are signed by the CA, and sets
+// them to the control plane config, the certificates are used by the control plane to serve and to
+// sign its internal certificates.
+func (d *controlPlaneDeployer) generateCerts() error {
+	certs, err := cert.GenerateAPIServerCertsWithCA(d.ca, d.config.Hostname, nil)
+	if err != nil {
+		return fmt.Errorf("failed to generate the certificates of the control plane: %v", err)
+	}
+
+	d.config.APIServerCA = certs.ServingCA
+	d.config.APIServerCAKey = certs.ServingCAKey
+	d.config.ServingCert = certs.ServingCert
+	d.config.ServingKey = certs.ServingCertKey
+	d.certs = certs
+	return nil
+}
+
+// placeholderCerts sets the placeholders of the certificates to the control plane config, so that no
+// certificate or key is generated or written out when the manifests are rendered.
+func (d *controlPlaneDeployer) placeholderCerts() {
+	fmt.Fprintf(os.Stderr, "The certificates of the control plane are signed by the CA when the cluster is connected, "+
+		"replace %s in the secret %s/%s with the base64 encoded certificates in the rendered manifests\n",
+		certificatePlaceholder, d.config.Namespace, controlPlaneConfigSecretName)
+
+	d.config.APIServerCA = []byte(certificatePlaceholder)
+	d.config.APIServerCAKey = []byte(certificatePlaceholder)
+	d.config.ServingCert = []byte(certificatePlaceholder)
+	d.config.ServingKey = []byte(certificatePlaceholder)
+}
+
+// buildKubeconfig builds the admin kubeconfig of the control plane with the client certificate that is
+// signed by the CA.
+func (d *controlPlaneDeployer) buildKubeconfig() error {
+	if d.certs == nil {
+		if err := d.generateCerts(); err != nil {
+			return err
+		}
+	}
+
+	kubeconfig, err := clientcmd.Write(configs.BuildControlPlaneKubeConfig(d.config.Hostname, d.certs))
+	if err != nil {
+		return fmt.Errorf("failed to build the kubeconfig of the control plane: %v", err)
+	}
+
+	d.config.ControlPlaneKubeConfig = kubeconfig
+	return nil
+}
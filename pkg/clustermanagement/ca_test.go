@@ -0,0 +1,294 @@
+package clustermanagement
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	fakecluster "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+
+	"github.com/skeeey/xcm-cli/pkg/cert"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+func TestValidateCA(t *testing.T) {
+	cases := []struct {
+		opts  CAOptions
+		valid bool
+	}{
+		{opts: CAOptions{}, valid: true},
+		{opts: CAOptions{CACertFile: "ca.crt", CAKeyFile: "ca.key"}, valid: true},
+		{opts: CAOptions{CASecret: "cert-manager/xcm-ca"}, valid: true},
+		{opts: CAOptions{CACertFile: "ca.crt"}, valid: false},
+		{opts: CAOptions{CAKeyFile: "ca.key"}, valid: false},
+		{opts: CAOptions{CACertFile: "ca.crt", CAKeyFile: "ca.key", CASecret: "cert-manager/xcm-ca"}, valid: false},
+		{opts: CAOptions{CASecret: "xcm-ca"}, valid: false},
+		{opts: CAOptions{CASecret: "/xcm-ca"}, valid: false},
+	}
+
+	for _, c := range cases {
+		err := c.opts.Validate()
+		if c.valid && err != nil {
+			t.Errorf("unexpected error with %v: %v", c.opts, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("expected error with %v", c.opts)
+		}
+	}
+}
+
+func TestControlPlaneObjectsWithCA(t *testing.T) {
+	ca, err := cert.NewSelfSignedCA("corp-ca", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyData, err := ca.KeyPEM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d := &controlPlaneDeployer{
+		kubeClient: fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "cert-manager", Name: "xcm-ca"},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: ca.CertPEM(), corev1.TLSPrivateKeyKey: keyData},
+		}),
+		config: &ControlPlaneConfig{Namespace: "xcm", Hostname: "cp.example.com:443"},
+		caOpts: CAOptions{CASecret: "cert-manager/xcm-ca"},
+	}
+
+	if status := d.checkCA(context.TODO()); !status.Healthy {
+		t.Errorf("expected the CA is healthy, but got %v", status)
+	}
+	if err := d.loadCA(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the certificates are signed by a sub CA, so that the key of the given CA isn't stored
+	if d.ca.Cert.Subject.CommonName != controlPlaneCAName {
+		t.Errorf("expected the sub CA of the control plane, but got %q", d.ca.Cert.Subject.CommonName)
+	}
+	if err := d.ca.Cert.CheckSignatureFrom(ca.Cert); err != nil {
+		t.Errorf("expected the sub CA is signed by the given CA: %v", err)
+	}
+	if err := d.generateCerts(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ocmConfig := string(d.renderOCMConfig())
+	for _, s := range []string{
+		"caFile: /controlplane_config/apiserver-ca.crt",
+		"caKeyFile: /controlplane_config/apiserver-ca.key",
+		"certFile: /controlplane_config/apiserver.crt",
+		"keyFile: /controlplane_config/apiserver.key",
+	} {
+		if !strings.Contains(ocmConfig, s) {
+			t.Errorf("expected %q in the ocm config, but got %s", s, ocmConfig)
+		}
+	}
+
+	d.config.OCMConfig = []byte(ocmConfig)
+	found := false
+	for _, obj := range d.controlPlaneObjects() {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok || secret.Data["apiserver-ca.crt"] == nil {
+			continue
+		}
+		found = true
+		if string(secret.Data["apiserver-ca.crt"]) != string(d.ca.CertPEM()) {
+			t.Errorf("expected the sub CA in the config secret")
+		}
+		if string(secret.Data["apiserver-ca.key"]) == string(keyData) {
+			t.Errorf("expected the key of the given CA isn't in the config secret")
+		}
+	}
+	if !found {
+		t.Errorf("expected the certificates in the config secret")
+	}
+
+	if err := d.buildKubeconfig(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err := clientcmd.Load(d.config.ControlPlaneKubeConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current := config.Contexts[config.CurrentContext]
+	if cluster := config.Clusters[current.Cluster]; cluster.Server != "https://cp.example.com:443" {
+		t.Errorf("unexpected server %q", cluster.Server)
+	}
+
+	// the client certificate of the kubeconfig is signed by the CA
+	block, _ := pem.Decode(config.AuthInfos[current.AuthInfo].ClientCertificateData)
+	if block == nil {
+		t.Fatalf("expected the client certificate in the kubeconfig")
+	}
+	clientCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(d.ca.Cert)
+	if _, err := clientCert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Errorf("failed to verify the client certificate: %v", err)
+	}
+}
+
+func TestControlPlaneRenderWithCA(t *testing.T) {
+	ca, err := cert.NewSelfSignedCA("corp-ca", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyData, err := ca.KeyPEM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d := &controlPlaneDeployer{
+		kubeClient: fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "cert-manager", Name: "xcm-ca"},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: ca.CertPEM(), corev1.TLSPrivateKeyKey: keyData},
+		}),
+		clusterClient: fakecluster.NewSimpleClientset(),
+		config:        &ControlPlaneConfig{Namespace: "xcm", Hostname: "cp.example.com:443"},
+		caOpts:        CAOptions{CASecret: "cert-manager/xcm-ca"},
+	}
+
+	manifests, err := d.Render(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.certs != nil {
+		t.Errorf("expected no certificate is generated when the manifests are rendered")
+	}
+
+	for _, obj := range manifests.Objects {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok || secret.Name != controlPlaneConfigSecretName {
+			continue
+		}
+		for _, key := range []string{apiServerCAKey, apiServerCAKeyKey, servingCertKey, servingKeyKey} {
+			if string(secret.Data[key]) != certificatePlaceholder {
+				t.Errorf("expected the placeholder of %s, but got %q", key, secret.Data[key])
+			}
+		}
+		return
+	}
+	t.Errorf("expected the config secret is rendered")
+}
+
+func TestLoadSubCA(t *testing.T) {
+	ca, err := cert.NewSelfSignedCA("corp-ca", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyData, err := ca.KeyPEM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	subCA, err := ca.NewSubCA(controlPlaneCAName, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	subCAKeyData, err := subCA.KeyPEM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	otherCA, err := cert.NewSelfSignedCA("other-ca", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	otherSubCA, err := otherCA.NewSubCA(controlPlaneCAName, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	otherSubCAKeyData, err := otherSubCA.KeyPEM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name        string
+		caCert      []byte
+		caKey       []byte
+		deployed    bool
+		reused      bool
+		expectedErr string
+	}{
+		{name: "no sub CA"},
+		{name: "sub CA", caCert: subCA.CertPEM(), caKey: subCAKeyData, deployed: true, reused: true},
+		{name: "other CA not deployed", caCert: otherSubCA.CertPEM(), caKey: otherSubCAKeyData},
+		{
+			name:        "other CA",
+			caCert:      otherSubCA.CertPEM(),
+			caKey:       otherSubCAKeyData,
+			deployed:    true,
+			expectedErr: "the control plane xcm/multicluster-controlplane is not signed by the given CA \"corp-ca\"",
+		},
+		{
+			name:        "own CAs",
+			caCert:      otherCA.CertPEM(),
+			deployed:    true,
+			expectedErr: "the control plane xcm/multicluster-controlplane is not signed by the given CA \"corp-ca\"",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objs := []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "cert-manager", Name: "xcm-ca"},
+				Type:       corev1.SecretTypeTLS,
+				Data:       map[string][]byte{corev1.TLSCertKey: ca.CertPEM(), corev1.TLSPrivateKeyKey: keyData},
+			}}
+			if c.caCert != nil {
+				objs = append(objs, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "xcm", Name: controlPlaneConfigSecretName},
+					Data:       map[string][]byte{apiServerCAKey: c.caCert, apiServerCAKeyKey: c.caKey},
+				})
+			}
+			if c.deployed {
+				objs = append(objs, &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "xcm", Name: constants.ControlPlaneName},
+				})
+			}
+
+			d := &controlPlaneDeployer{
+				kubeClient: fake.NewSimpleClientset(objs...),
+				config:     &ControlPlaneConfig{Namespace: "xcm"},
+				caOpts:     CAOptions{CASecret: "cert-manager/xcm-ca"},
+			}
+			err := d.loadCA(context.TODO())
+			if c.expectedErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), c.expectedErr) {
+					t.Fatalf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if reused := d.ca.Cert.Equal(subCA.Cert); reused != c.reused {
+				t.Errorf("expected the sub CA is reused %v, but got %v", c.reused, reused)
+			}
+			if err := d.ca.Cert.CheckSignatureFrom(ca.Cert); err != nil || d.ca.Cert.Equal(ca.Cert) {
+				t.Errorf("expected the control plane is signed by a sub CA of the given CA")
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package clustermanagement
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/yaml"
+
+	"github.com/skeeey/xcm-cli/pkg/cert"
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/managedcluster"
+)
+
+// The keys of the control plane config secret, see manifests/connector/controlplane-config-secret.yaml.
+const (
+	controlPlaneConfigSecretName = "controlplane-config"
+	ocmConfigKey                 = "ocmconfig.yaml"
+	apiServerCAKey               = "apiserver-ca.crt"
+	apiServerCAKeyKey            = "apiserver-ca.key"
+	servingCertKey               = "apiserver.crt"
+	servingKeyKey                = "apiserver.key"
+)
+
+// dialTimeout is the timeout to connect to the control plane to get its serving certificate.
+const dialTimeout = 10 * time.Second
+
+// CheckControlPlaneCertificates returns the serving certificate of the control plane and the client
+// certificate of its admin kubeconfig, the serving certificate is got from the control plane.
+func CheckControlPlaneCertificates(kubeconfig []byte, threshold time.Duration) ([]*cert.CertificateInfo, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig of the control plane: %v", err)
+	}
+
+	current, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("the current context of the kubeconfig of the control plane is not found")
+	}
+	cluster, ok := config.Clusters[current.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("the cluster %q of the kubeconfig of the control plane is not found", current.Cluster)
+	}
+
+	infos := []*cert.CertificateInfo{}
+	errs := []error{}
+
+	serving, err := servingCertificate(cluster.Server, cluster.TLSServerName)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to get the serving certificate of the control plane: %v", err))
+	} else {
+		infos = append(infos, cert.Inspect("controlplane/serving", serving, threshold))
+	}
+
+	client, err := clientCertificate(config.AuthInfos[current.AuthInfo])
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to get the client certificate of the admin kubeconfig: %v", err))
+	} else {
+		infos = append(infos, cert.Inspect("controlplane/admin", client, threshold))
+	}
+
+	return infos, utilerrors.NewAggregate(errs)
+}
+
+// CheckAgentCertificate returns the client certificate that the agent on the relayed cluster connects
+// to the control plane with.
+func CheckAgentCertificate(ctx context.Context, kubeconfigPath, clusterID string,
+	threshold time.Duration) (*cert.CertificateInfo, error) {
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return agentCertificate(ctx, kubeClient, clusterID, threshold)
+}
+
+func agentCertificate(ctx context.Context, kubeClient kubernetes.Interface, clusterID string,
+	threshold time.Duration) (*cert.CertificateInfo, error) {
+	secret, err := kubeClient.CoreV1().Secrets(constants.DefaultControlPlaneAgentNamespace).Get(
+		ctx, constants.HubKubeconfigSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the hub kubeconfig secret of cluster %s: %v", clusterID, err)
+	}
+
+	clientCert, err := cert.ParseCertificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return nil, fmt.Errorf("the hub kubeconfig secret of cluster %s is invalid: %v", clusterID, err)
+	}
+
+	return cert.Inspect(fmt.Sprintf("agent/%s", clusterID), clientCert, threshold), nil
+}
+
+// servingCertificate returns the serving certificate of the server. The certificate is inspected
+// rather than trusted, it's not verified, so that an expired certificate can be reported too.
+func servingCertificate(server, serverName string) (*x509.Certificate, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "443")
+	}
+	if serverName == "" {
+		serverName = u.Hostname()
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", address, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true, // #nosec G402 the certificate is only inspected
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("the server %s has no serving certificate", server)
+	}
+
+	return certs[0], nil
+}
+
+// clientCertificate returns the client certificate of the auth info.
+func clientCertificate(authInfo *clientcmdapi.AuthInfo) (*x509.Certificate, error) {
+	if authInfo == nil {
+		return nil, fmt.Errorf("the auth info is not found")
+	}
+
+	data := authInfo.ClientCertificateData
+	if len(data) == 0 && authInfo.ClientCertificate != "" {
+		fileData, err := os.ReadFile(authInfo.ClientCertificate)
+		if err != nil {
+			return nil, err
+		}
+		data = fileData
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("the kubeconfig doesn't authenticate with a client certificate")
+	}
+
+	return cert.ParseCertificate(data)
+}
+
+// RotateCertificates regenerates the serving certificate of the control plane and the client
+// certificate of its admin kubeconfig with the CA that the control plane was connected with, then the
+// control plane is restarted to serve with the new certificate and the admin kubeconfig is updated.
+// An error is returned if the control plane generates its own CAs, their keys are only in its pod.
+func (d *controlPlaneDeployer) RotateCertificates(ctx context.Context, w io.Writer) error {
+	id, err := managedcluster.GetClusterClaim(ctx, d.clusterClient, constants.ClusterIDClaimName)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster claim: %v", err)
+	}
+	if id == "" {
+		return fmt.Errorf("the cluster is not connected to xCM")
+	}
+	d.controlPlaneID = id
+
+	secret, err := d.kubeClient.CoreV1().Secrets(d.config.Namespace).Get(
+		ctx, controlPlaneConfigSecretName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the config secret of the control plane: %v", err)
+	}
+
+	if len(secret.Data[apiServerCAKeyKey]) == 0 {
+		return fmt.Errorf("the certificates of the control plane are signed by its own CAs, which cannot be " +
+			"rotated, disconnect the cluster and connect it again to regenerate them, or connect it with " +
+			"'--ca-cert' or '--ca-secret' to rotate them")
+	}
+
+	ca, err := cert.LoadCA(secret.Data[apiServerCAKey], secret.Data[apiServerCAKeyKey])
+	if err != nil {
+		return fmt.Errorf("the CA of the control plane is invalid: %v", err)
+	}
+	d.ca = ca
+
+	hostname, err := ocmConfigHostname(secret.Data[ocmConfigKey])
+	if err != nil {
+		return err
+	}
+	d.config.Hostname = hostname
+
+	if err := d.generateCerts(); err != nil {
+		return err
+	}
+
+	required := secret.DeepCopy()
+	required.Data[servingCertKey] = d.config.ServingCert
+	required.Data[servingKeyKey] = d.config.ServingKey
+	if _, err := d.kubeClient.CoreV1().Secrets(d.config.Namespace).Update(ctx, required, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update the config secret of the control plane: %v", err)
+	}
+	fmt.Fprintf(w, "secret/%s updated\n", controlPlaneConfigSecretName)
+
+	if err := d.buildKubeconfig(); err != nil {
+		return err
+	}
+
+	if err := d.updateKubeconfigSecret(ctx, w); err != nil {
+		return err
+	}
+
+	if err := restartDeployment(ctx, w, d.kubeClient, d.config.Namespace, constants.ControlPlaneName); err != nil {
+		return fmt.Errorf("failed to restart the control plane: %v", err)
+	}
+
+	// the standby connectors reload the kubeconfig of the control plane that is signed again
+	standby, err := d.standbyConnectorDeployed(ctx)
+	if err != nil {
+		return err
+	}
+	if standby {
+		if err := restartDeployment(ctx, w, d.kubeClient, d.config.Namespace, constants.StandbyConnectorName); err != nil {
+			return fmt.Errorf("failed to restart the connector: %v", err)
+		}
+	}
+
+	if err := configs.UpdateControlPlaneKubeConfig(id, d.config.ControlPlaneKubeConfig); err != nil {
+		return fmt.Errorf("failed to save control plane kubeconfig: %v", err)
+	}
+
+	return nil
+}
+
+// updateKubeconfigSecret updates the admin kubeconfig in the kubeconfig secret of the control plane,
+// the secret is skipped if it's not found.
+func (d *controlPlaneDeployer) updateKubeconfigSecret(ctx context.Context, w io.Writer) error {
+	secret, err := d.kubeClient.CoreV1().Secrets(d.config.Namespace).Get(
+		ctx, constants.ControlPlaneKubeconfigSecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	required := secret.DeepCopy()
+	if required.Data == nil {
+		required.Data = map[string][]byte{}
+	}
+	required.Data["kubeconfig"] = d.config.ControlPlaneKubeConfig
+	if _, err := d.kubeClient.CoreV1().Secrets(d.config.Namespace).Update(ctx, required, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update the kubeconfig secret of the control plane: %v", err)
+	}
+
+	fmt.Fprintf(w, "secret/%s updated\n", constants.ControlPlaneKubeconfigSecretName)
+	return nil
+}
+
+// ocmConfigHostname returns the external host of the control plane in the ocm config, the certificates
+// are rotated for the host that the control plane was connected with.
+func ocmConfigHostname(data []byte) (string, error) {
+	ocmConfig := struct {
+		APIServer struct {
+			ExternalHostname string `json:"externalHostname"`
+		} `json:"apiserver"`
+	}{}
+	if err := yaml.Unmarshal(data, &ocmConfig); err != nil {
+		return "", fmt.Errorf("failed to parse the ocm config of the control plane: %v", err)
+	}
+
+	if ocmConfig.APIServer.ExternalHostname == "" {
+		return "", fmt.Errorf("the host of the control plane is not found in the ocm config")
+	}
+
+	return ocmConfig.APIServer.ExternalHostname, nil
+}
@@ -0,0 +1,191 @@
+package clustermanagement
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	fakecluster "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+
+	"github.com/skeeey/xcm-cli/pkg/cert"
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/credstore"
+)
+
+func TestCheckControlPlaneCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ca, err := cert.NewSelfSignedCA("ca", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certData, keyData, err := ca.NewCert(&cert.CertConfig{
+		CommonName: "system:admin",
+		Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeconfig, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"cluster": {Server: server.URL}},
+		AuthInfos:      map[string]*clientcmdapi.AuthInfo{"auth": {ClientCertificateData: certData, ClientKeyData: keyData}},
+		Contexts:       map[string]*clientcmdapi.Context{"context": {Cluster: "cluster", AuthInfo: "auth"}},
+		CurrentContext: "context",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	infos, err := CheckControlPlaneCertificates(kubeconfig, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("expected the serving and the client certificates, but got %v", infos)
+	}
+	if serving := infos[0]; serving.Name != "controlplane/serving" ||
+		!serving.NotAfter.Equal(server.Certificate().NotAfter) {
+		t.Errorf("unexpected serving certificate %v", serving)
+	}
+	if admin := infos[1]; admin.Name != "controlplane/admin" || admin.Subject != "CN=system:admin" ||
+		admin.Issuer != "CN=ca" || admin.Status != cert.StatusValid {
+		t.Errorf("unexpected client certificate %v", admin)
+	}
+
+	info, err := agentCertificate(context.TODO(), fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: constants.DefaultControlPlaneAgentNamespace,
+			Name:      constants.HubKubeconfigSecretName,
+		},
+		Data: map[string][]byte{corev1.TLSCertKey: certData},
+	}), "c1", 20*365*24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Name != "agent/c1" || info.Status != cert.StatusExpiring {
+		t.Errorf("unexpected agent certificate %v", info)
+	}
+
+	if _, err := agentCertificate(context.TODO(), fake.NewSimpleClientset(), "c1", time.Hour); err == nil {
+		t.Errorf("expected error when the hub kubeconfig secret is not found")
+	}
+}
+
+func TestRotateCertificates(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(credstore.BackendEnv, credstore.BackendFile)
+
+	corpCA, err := cert.NewSelfSignedCA("corp-ca", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the config secret has the sub CA of the control plane
+	ca, err := corpCA.NewSubCA(controlPlaneCAName, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	caKeyData, err := ca.KeyPEM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d := &controlPlaneDeployer{
+		config: &ControlPlaneConfig{Namespace: "xcm", Hostname: "cp.example.com:443"},
+		ca:     ca,
+	}
+	if err := d.generateCerts(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	previousServingCert := d.config.ServingCert
+
+	d.kubeClient = fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xcm", Name: controlPlaneConfigSecretName},
+			Data: map[string][]byte{
+				ocmConfigKey:      d.renderOCMConfig(),
+				apiServerCAKey:    ca.CertPEM(),
+				apiServerCAKeyKey: caKeyData,
+				servingCertKey:    d.config.ServingCert,
+				servingKeyKey:     d.config.ServingKey,
+			},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xcm", Name: constants.ControlPlaneName},
+			Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+		},
+	)
+	d.clusterClient = fakecluster.NewSimpleClientset(&clusterv1alpha1.ClusterClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: constants.ClusterIDClaimName},
+		Spec:       clusterv1alpha1.ClusterClaimSpec{Value: "cp1"},
+	})
+	// the CA and the host of the control plane are loaded from the config secret
+	d.ca, d.certs, d.config = nil, nil, &ControlPlaneConfig{Namespace: "xcm"}
+
+	out := &bytes.Buffer{}
+	if err := d.RotateCertificates(context.TODO(), out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secret, err := d.kubeClient.CoreV1().Secrets("xcm").Get(context.TODO(), controlPlaneConfigSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Equal(secret.Data[servingCertKey], previousServingCert) {
+		t.Errorf("expected the serving certificate is rotated")
+	}
+	servingCert, err := cert.ParseCertificate(secret.Data[servingCertKey])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := servingCert.VerifyHostname("cp.example.com"); err != nil {
+		t.Errorf("expected the serving certificate is for the host of the control plane: %v", err)
+	}
+
+	deploy, err := d.kubeClient.AppsV1().Deployments("xcm").Get(context.TODO(), constants.ControlPlaneName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deploy.Spec.Template.Annotations[restartedAtAnnotation] == "" {
+		t.Errorf("expected the control plane is restarted")
+	}
+
+	kubeconfig, err := configs.ControlPlaneKubeConfig("cp1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(kubeconfig, d.config.ControlPlaneKubeConfig) {
+		t.Errorf("expected the admin kubeconfig is saved")
+	}
+}
+
+func TestRotateCertificatesWithoutCA(t *testing.T) {
+	d := &controlPlaneDeployer{
+		kubeClient: fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xcm", Name: controlPlaneConfigSecretName},
+			Data:       map[string][]byte{ocmConfigKey: []byte("apiserver:\n  externalHostname: cp.example.com\n")},
+		}),
+		clusterClient: fakecluster.NewSimpleClientset(&clusterv1alpha1.ClusterClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: constants.ClusterIDClaimName},
+			Spec:       clusterv1alpha1.ClusterClaimSpec{Value: "cp1"},
+		}),
+		config: &ControlPlaneConfig{Namespace: "xcm"},
+	}
+
+	if err := d.RotateCertificates(context.TODO(), &bytes.Buffer{}); err == nil {
+		t.Errorf("expected error when the control plane generates its own CAs")
+	}
+}
@@ -0,0 +1,84 @@
+package clustermanagement
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/managedcluster"
+)
+
+// clusterInfoClaimNames are the names of the cluster claims that describe a cluster.
+var clusterInfoClaimNames = []string{
+	constants.ProductClaimName,
+	constants.PlatformClaimName,
+	constants.RegionClaimName,
+	constants.KubeVersionClaimName,
+}
+
+// applyClusterClaims detects the claims of a cluster and applies them on the cluster, the given
+// claims take precedence over the detected ones.
+func applyClusterClaims(ctx context.Context,
+	kubeClient kubernetes.Interface, clusterClient clusterclient.Interface, claims map[string]string) error {
+	clusterClaims, err := buildClusterClaims(ctx, kubeClient, claims)
+	if err != nil {
+		return err
+	}
+
+	for _, claim := range clusterClaims {
+		if err := managedcluster.ApplyClusterClaim(ctx, clusterClient, claim); err != nil {
+			return fmt.Errorf("failed to apply cluster claim %s: %v", claim.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// buildClusterClaims detects the claims of a cluster and returns them sorted by the name, the given
+// claims take precedence over the detected ones.
+func buildClusterClaims(ctx context.Context,
+	kubeClient kubernetes.Interface, claims map[string]string) ([]*clusterv1alpha1.ClusterClaim, error) {
+	detected, err := managedcluster.DetectClusterClaims(ctx, kubeClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect cluster claims: %v", err)
+	}
+
+	for name, value := range claims {
+		detected[name] = value
+	}
+
+	names := []string{}
+	for name := range detected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	clusterClaims := []*clusterv1alpha1.ClusterClaim{}
+	for _, name := range names {
+		clusterClaims = append(clusterClaims, newClusterClaim(name, detected[name]))
+	}
+
+	return clusterClaims, nil
+}
+
+func newClusterClaim(name, value string) *clusterv1alpha1.ClusterClaim {
+	return &clusterv1alpha1.ClusterClaim{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clusterv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "ClusterClaim",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: clusterv1alpha1.ClusterClaimSpec{
+			Value: value,
+		},
+	}
+}
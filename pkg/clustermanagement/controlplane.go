@@ -0,0 +1,700 @@
+package clustermanagement
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/skeeey/xcm-cli/pkg/cert"
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/genericflags"
+	"github.com/skeeey/xcm-cli/pkg/helpers"
+	"github.com/skeeey/xcm-cli/pkg/managedcluster"
+	"github.com/skeeey/xcm-cli/pkg/resource"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+)
+
+const ocmconfigfile = "manifests/connector/ocmconfig.yaml"
+
+// hostnamePlaceholder is the host of the control plane in the rendered manifests when the load
+// balancer is not provisioned.
+const hostnamePlaceholder = "CONTROL_PLANE_HOSTNAME"
+
+// certificatePlaceholder is the certificates and the keys of the control plane in the rendered
+// manifests when the control plane is connected with a CA.
+const certificatePlaceholder = "CONTROL_PLANE_CERTIFICATE"
+
+var serviceFiles = []string{
+	"manifests/connector/namespace.yaml",
+	"manifests/connector/service.yaml",
+}
+
+var controlPlaneFiles = []string{
+	"manifests/connector/clusterrolebinding.yaml",
+	"manifests/connector/serviceaccount.yaml",
+	"manifests/connector/controlplane-config-secret.yaml",
+	"manifests/connector/deployment.yaml",
+}
+
+const connectorImagePullSecretFile = "manifests/connector/image-pull-secret.yaml"
+
+//go:embed manifests
+var manifestFiles embed.FS
+
+type ControlPlaneConfig struct {
+	ControlPlaneKubeConfig []byte
+	OCMConfig              []byte
+	Namespace              string
+	Hostname               string
+	XCMServer              string
+	ServiceType            corev1.ServiceType
+	ExternalHostname       string
+	ControlPlaneImage      string
+	ConnectorImage         string
+	ImagePullSecret        string
+	ImagePullSecretData    []byte
+	Replicas               int32
+	StandbyReplicas        int32
+	StorageSize            string
+	StorageClass           string
+	StorageAccessMode      corev1.PersistentVolumeAccessMode
+	EtcdServers            []string
+	EtcdCA                 []byte
+	EtcdCert               []byte
+	EtcdKey                []byte
+	RBACMode               string
+	APIServerCA            []byte
+	APIServerCAKey         []byte
+	ServingCert            []byte
+	ServingKey             []byte
+}
+
+// controlPlaneDeployer deploys the xCM connector on a hosting cluster, it is shared by the providers,
+// each provider customizes the cluster claims and the host of the control plane.
+type controlPlaneDeployer struct {
+	kubeClient     kubernetes.Interface
+	dynamicClient  dynamic.Interface
+	clusterClient  clusterclient.Interface
+	config         *ControlPlaneConfig
+	images         ImageOptions
+	expose         ExposeOptions
+	storage        StorageOptions
+	rbac           RBACOptions
+	caOpts         CAOptions
+	prune          bool
+	controlPlaneID string
+
+	// ca signs the certificates of the control plane, certs are the certificates that are signed by
+	// it, the control plane generates its own CAs if it's nil.
+	ca    *cert.CA
+	certs *cert.APIServerCerts
+
+	// claims are the cluster claims of the hosting cluster that take precedence over the detected
+	// claims, the key is the claim name.
+	claims map[string]string
+
+	// hostname returns the host of the control plane from the load balancer ingress.
+	hostname func(ingress corev1.LoadBalancerIngress) string
+}
+
+func newControlPlaneDeployer(opts *DeployerOptions) (*controlPlaneDeployer, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", opts.KubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterClient, err := clusterclient.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ControlPlaneConfig{
+		Namespace:         opts.Namespace,
+		XCMServer:         opts.XCMServer,
+		ServiceType:       opts.serviceType(),
+		ExternalHostname:  opts.ExternalHostname,
+		ControlPlaneImage: opts.image(opts.ControlPlaneImage, constants.DefaultControlPlaneImage),
+		ConnectorImage:    opts.image(opts.ConnectorImage, constants.DefaultConnectorImage),
+		ImagePullSecret:   opts.imagePullSecretName(),
+		Replicas:          opts.replicas(),
+		StandbyReplicas:   opts.standbyConnectorReplicas(),
+		StorageSize:       opts.StorageSize,
+		StorageClass:      opts.StorageClass,
+		StorageAccessMode: opts.accessMode(),
+		EtcdServers:       opts.EtcdServers,
+		RBACMode:          opts.RBACOptions.mode(),
+	}
+	if err := opts.loadEtcdCerts(config); err != nil {
+		return nil, err
+	}
+
+	return &controlPlaneDeployer{
+		kubeClient:    kubeClient,
+		dynamicClient: dynamicClient,
+		clusterClient: clusterClient,
+		config:        config,
+		images:        opts.ImageOptions,
+		expose:        opts.ExposeOptions,
+		storage:       opts.StorageOptions,
+		rbac:          opts.RBACOptions,
+		caOpts:        opts.CAOptions,
+		prune:         opts.Prune,
+		claims:        map[string]string{},
+		hostname: func(ingress corev1.LoadBalancerIngress) string {
+			if ingress.Hostname != "" {
+				return ingress.Hostname
+			}
+			return ingress.IP
+		},
+	}, nil
+}
+
+func (d *controlPlaneDeployer) Connect(ctx context.Context) error {
+	if err := d.loadImagePullSecret(ctx); err != nil {
+		return err
+	}
+
+	if err := d.loadCA(ctx); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stdout, "Deploy the xCM connector [connector] ...")
+	if err := d.ensureControlPlane(ctx); err != nil {
+		return fmt.Errorf("failed to deploy connector: %v", err)
+	}
+
+	objects := append(d.exposeObjects(), d.controlPlaneObjects()...)
+	if err := recordInventory(ctx, os.Stdout, d.applier(), d.inventory(), d.prune, objects); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stdout, "Connect to xCM ...")
+	id, err := managedcluster.CreateClusterClaim(ctx, d.clusterClient, &clusterv1alpha1.ClusterClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: constants.ClusterIDClaimName,
+		},
+		Spec: clusterv1alpha1.ClusterClaimSpec{
+			Value: managedcluster.GetClusterID(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create cluster claim: %v", err)
+	}
+
+	d.controlPlaneID = id
+
+	if err := configs.SaveControlPlaneKubeConfig(id, d.config.ControlPlaneKubeConfig); err != nil {
+		return fmt.Errorf("failed to save control plane kubeconfig: %v", err)
+	}
+
+	if err := applyClusterClaims(ctx, d.kubeClient, d.clusterClient, d.claims); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Connected checks if the xCM connector is deployed on the cluster.
+func (d *controlPlaneDeployer) Connected(ctx context.Context) (bool, error) {
+	_, err := d.kubeClient.CoreV1().Namespaces().Get(ctx, d.config.Namespace, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Disconnect removes the xCM connector and the cluster claims from the cluster, and deletes the
+// local control plane context.
+func (d *controlPlaneDeployer) Disconnect(ctx context.Context) error {
+	id, err := managedcluster.GetClusterClaim(ctx, d.clusterClient, constants.ClusterIDClaimName)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster claim: %v", err)
+	}
+	d.controlPlaneID = id
+
+	fmt.Fprintln(os.Stdout, "Remove the xCM connector [connector] ...")
+	objects := append(d.exposeObjects(), d.controlPlaneObjects()...)
+	// the objects that were applied by a previous version but are no longer rendered are removed too
+	if _, err := d.inventory().Prune(ctx, os.Stdout, d.applier(), objects...); err != nil {
+		return fmt.Errorf("failed to remove connector: %v", err)
+	}
+	if err := d.applier().Delete(ctx, reverse(objects)...); err != nil {
+		return fmt.Errorf("failed to remove connector: %v", err)
+	}
+
+	if err := waitForNamespaceDeleted(ctx, d.kubeClient, d.config.Namespace); err != nil {
+		return fmt.Errorf("failed to remove namespace %s: %v", d.config.Namespace, err)
+	}
+
+	fmt.Fprintln(os.Stdout, "Disconnect from xCM ...")
+	if err := managedcluster.DeleteClusterClaims(ctx, d.clusterClient,
+		append([]string{constants.ClusterIDClaimName}, clusterInfoClaimNames...)...); err != nil {
+		return fmt.Errorf("failed to delete cluster claims: %v", err)
+	}
+
+	if id == "" {
+		return nil
+	}
+
+	if err := configs.DeleteControlPlaneContext(id); err != nil {
+		return fmt.Errorf("failed to delete control plane context: %v", err)
+	}
+
+	return nil
+}
+
+func (d *controlPlaneDeployer) ensureControlPlane(ctx context.Context) error {
+	if err := d.ensureExposure(ctx); err != nil {
+		return err
+	}
+
+	// the objects are applied on every connect, so that the objects that are changed or added since the
+	// previous connect are applied before the inventory is recorded or pruned
+	if err := d.deployControlPlane(ctx); err != nil {
+		return err
+	}
+
+	// the admin kubeconfig is built locally with a client certificate that is signed by the given CA
+	if d.ca != nil {
+		return d.buildKubeconfig()
+	}
+
+	if err := wait.PollImmediate(1*time.Second, genericflags.TimeOut(), func() (bool, error) {
+		adminSecret, err := d.kubeClient.CoreV1().Secrets(d.config.Namespace).Get(
+			ctx, constants.ControlPlaneKubeconfigSecretName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		kubeconfigData, ok := adminSecret.Data["kubeconfig"]
+		if !ok {
+			return false, fmt.Errorf("the kubeconfig is not from the secret %s/%s",
+				d.config.Namespace, constants.ControlPlaneKubeconfigSecretName)
+		}
+
+		d.config.ControlPlaneKubeConfig = kubeconfigData
+		return true, nil
+	}); err != nil {
+		return fmt.Errorf("control plane is degraded, the kubeconfig secret %s/%s is not ready: %v",
+			d.config.Namespace, constants.ControlPlaneKubeconfigSecretName, err)
+	}
+
+	return nil
+}
+
+func (d *controlPlaneDeployer) Upgrade(ctx context.Context, w io.Writer) error {
+	id, err := managedcluster.GetClusterClaim(ctx, d.clusterClient, constants.ClusterIDClaimName)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster claim: %v", err)
+	}
+	d.controlPlaneID = id
+
+	// the image pull secret is copied into the namespace again, so that the new images can be pulled
+	// with the new credentials
+	if d.images.ImagePullSecret != "" {
+		if err := d.loadImagePullSecret(ctx); err != nil {
+			return err
+		}
+		if err := d.applier().Apply(ctx, d.inventory().Label(
+			mustCreateObjects([]string{connectorImagePullSecretFile}, d.config)...)...); err != nil {
+			return err
+		}
+	}
+
+	if err := rolloutImages(ctx, w, d.kubeClient, d.config.Namespace, constants.ControlPlaneName, map[string]string{
+		"controlplane": d.config.ControlPlaneImage,
+		"connector":    d.config.ConnectorImage,
+	}, d.config.ImagePullSecret); err != nil {
+		return err
+	}
+
+	standby, err := d.standbyConnectorDeployed(ctx)
+	if err != nil || !standby {
+		return err
+	}
+
+	return rolloutImages(ctx, w, d.kubeClient, d.config.Namespace, constants.StandbyConnectorName, map[string]string{
+		"connector": d.config.ConnectorImage,
+	}, d.config.ImagePullSecret)
+}
+
+// standbyConnectorDeployed returns true if the connector is deployed with more than one replica, the
+// replicas besides the connector in the control plane pod are deployed separately.
+func (d *controlPlaneDeployer) standbyConnectorDeployed(ctx context.Context) (bool, error) {
+	_, err := d.kubeClient.AppsV1().Deployments(d.config.Namespace).Get(ctx, constants.StandbyConnectorName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
+
+func (d *controlPlaneDeployer) GetControlPlaneID() string {
+	return d.controlPlaneID
+}
+
+// Render returns the objects of the connector and the cluster claims. If the exposure of the control
+// plane is not ready, its host is unknown, a placeholder is used instead. The certificates that are
+// signed by the CA are generated only when the cluster is connected, placeholders are used instead.
+func (d *controlPlaneDeployer) Render(ctx context.Context) (*Manifests, error) {
+	id, err := managedcluster.GetClusterClaim(ctx, d.clusterClient, constants.ClusterIDClaimName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster claim: %v", err)
+	}
+	if id == "" {
+		id = managedcluster.GetClusterID()
+	}
+	d.controlPlaneID = id
+
+	if err := d.loadImagePullSecret(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := d.loadCA(ctx); err != nil {
+		return nil, err
+	}
+
+	if d.config.Hostname == "" {
+		hostname, err := d.resolveHostname(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if hostname == "" {
+			fmt.Fprintf(os.Stderr, "The host of the control plane is not ready, "+
+				"replace %s with its host in the rendered manifests\n", hostnamePlaceholder)
+			hostname = hostnamePlaceholder
+		}
+		d.config.Hostname = hostname
+	}
+	if d.ca != nil {
+		d.placeholderCerts()
+	}
+	d.config.OCMConfig = d.renderOCMConfig()
+
+	claims, err := buildClusterClaims(ctx, d.kubeClient, d.claims)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := append(d.exposeObjects(), d.controlPlaneObjects()...)
+	objects = append(objects, newClusterClaim(constants.ClusterIDClaimName, id))
+	for _, claim := range claims {
+		objects = append(objects, claim)
+	}
+
+	return &Manifests{Objects: objects}, nil
+}
+
+func (d *controlPlaneDeployer) DryRun(ctx context.Context, w io.Writer, strategy string) error {
+	manifests, err := d.Render(ctx)
+	if err != nil {
+		return err
+	}
+
+	return dryRun(ctx, w, strategy, manifests, d.dynamicClient, nil)
+}
+
+// exposeObjects returns the namespace, the control plane service and the objects that expose the
+// control plane service.
+func (d *controlPlaneDeployer) exposeObjects() []runtime.Object {
+	files := append([]string{}, serviceFiles...)
+	return d.inventory().Label(mustCreateObjects(append(files, d.expose.exposeFiles()...), d.config)...)
+}
+
+// controlPlaneObjects returns the objects of the control plane, the image pull secret and the
+// persistent volume claim of the data directory are created before the deployment, the roles are
+// created in the minimal rbac mode.
+func (d *controlPlaneDeployer) controlPlaneObjects() []runtime.Object {
+	files := d.rbac.rbacFiles(controlPlaneFiles)
+	if d.config.ImagePullSecret != "" {
+		files = append([]string{connectorImagePullSecretFile}, files...)
+	}
+	if d.config.StorageSize != "" {
+		files = append([]string{dataVolumeClaimFile}, files...)
+	}
+	if d.config.StandbyReplicas > 0 {
+		files = append(files, standbyConnectorFile)
+	}
+
+	return d.inventory().Label(mustCreateObjects(files, d.config)...)
+}
+
+// loadImagePullSecret loads the docker config of the image pull secret that is copied into the
+// namespace of the connector.
+func (d *controlPlaneDeployer) loadImagePullSecret(ctx context.Context) error {
+	data, err := d.images.loadImagePullSecret(ctx, d.kubeClient)
+	if err != nil {
+		return err
+	}
+
+	d.config.ImagePullSecretData = data
+	return nil
+}
+
+func (d *controlPlaneDeployer) renderOCMConfig() []byte {
+	template, err := manifestFiles.ReadFile(ocmconfigfile)
+	if err != nil {
+		// this should not happen, if happened, panic here
+		panic(err)
+	}
+
+	return resource.MustRenderFromTemplate(ocmconfigfile, template, d.config)
+}
+
+// Preflight checks the permissions to deploy the connector, the namespace, the load balancer of the
+// load balancer exposure, the cluster claim CRD and the images of the connector.
+func (d *controlPlaneDeployer) Preflight(ctx context.Context) ([]ComponentStatus, error) {
+	objects := append(d.exposeObjects(), d.controlPlaneObjects()...)
+
+	statuses := []ComponentStatus{
+		checkPermissions(ctx, d.kubeClient, objects),
+		checkNamespaces(ctx, d.kubeClient, d.config.Namespace),
+		checkClusterClaimCRD(d.kubeClient),
+		checkImages(ctx, d.dynamicClient, objects),
+	}
+
+	if d.rbac.mode() == RBACModeMinimal {
+		statuses = append(statuses, checkMinimalRBAC())
+	}
+
+	if d.expose.mode() == ExposeLoadBalancer {
+		statuses = append(statuses, checkLoadBalancer(ctx, d.kubeClient))
+	}
+
+	if d.images.ImagePullSecret != "" {
+		statuses = append(statuses, checkImagePullSecret(ctx, d.kubeClient, &d.images))
+	}
+
+	if d.storage.StorageSize != "" {
+		statuses = append(statuses, checkStorageClass(ctx, d.kubeClient, d.storage.StorageClass))
+	}
+
+	if d.caOpts.enabled() {
+		statuses = append(statuses, d.checkCA(ctx))
+	}
+
+	return statuses, nil
+}
+
+func (d *controlPlaneDeployer) Status(ctx context.Context) ([]ComponentStatus, error) {
+	id, err := managedcluster.GetClusterClaim(ctx, d.clusterClient, constants.ClusterIDClaimName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster claim: %v", err)
+	}
+	d.controlPlaneID = id
+
+	statuses := []ComponentStatus{}
+
+	connector := ComponentStatus{Name: "connector"}
+	deploy, err := d.kubeClient.AppsV1().Deployments(d.config.Namespace).Get(
+		ctx, constants.ControlPlaneName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		connector.Reason = fmt.Sprintf("the deployment %s/%s is not found", d.config.Namespace, constants.ControlPlaneName)
+	case err != nil:
+		return nil, err
+	case helpers.NumOfUnavailablePod(deploy) > 0:
+		connector.Reason = fmt.Sprintf("%d pod(s) of the deployment %s/%s are unavailable",
+			helpers.NumOfUnavailablePod(deploy), d.config.Namespace, constants.ControlPlaneName)
+	default:
+		connector.Healthy = true
+	}
+	statuses = append(statuses, connector)
+
+	kubeconfig := ComponentStatus{Name: "control plane kubeconfig"}
+	secret, err := d.kubeClient.CoreV1().Secrets(d.config.Namespace).Get(
+		ctx, constants.ControlPlaneKubeconfigSecretName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		kubeconfig.Reason = fmt.Sprintf("the secret %s/%s is not found",
+			d.config.Namespace, constants.ControlPlaneKubeconfigSecretName)
+	case err != nil:
+		return nil, err
+	case len(secret.Data["kubeconfig"]) == 0:
+		kubeconfig.Reason = fmt.Sprintf("the kubeconfig is not found in the secret %s/%s",
+			d.config.Namespace, constants.ControlPlaneKubeconfigSecretName)
+	default:
+		kubeconfig.Healthy = true
+	}
+	statuses = append(statuses, kubeconfig)
+
+	storage, err := checkDataVolumeClaim(ctx, d.kubeClient, d.config.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if storage != nil {
+		statuses = append(statuses, *storage)
+	}
+
+	exposure, err := d.checkExposure(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses = append(statuses, exposure)
+
+	return statuses, nil
+}
+
+// ensureExposure exposes the control plane service and waits until the host of the control plane is
+// resolved.
+func (d *controlPlaneDeployer) ensureExposure(ctx context.Context) error {
+	objects := d.exposeObjects()
+
+	return wait.PollImmediate(1*time.Second, genericflags.TimeOut(), func() (bool, error) {
+		err := d.applier().Apply(ctx, objects...)
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		hostname, err := d.resolveHostname(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		if hostname == "" {
+			return false, nil
+		}
+
+		d.config.Hostname = hostname
+		return true, nil
+	})
+}
+
+func (d *controlPlaneDeployer) deployControlPlane(ctx context.Context) error {
+	if d.ca != nil {
+		if err := d.generateCerts(); err != nil {
+			return err
+		}
+	}
+	d.config.OCMConfig = d.renderOCMConfig()
+
+	objects := d.controlPlaneObjects()
+
+	if err := wait.PollImmediate(1*time.Second, genericflags.TimeOut(), func() (done bool, err error) {
+		applyErr := d.applier().Apply(ctx, objects...)
+		if errors.IsNotFound(applyErr) {
+			return false, nil
+		}
+
+		if applyErr != nil {
+			return false, applyErr
+		}
+
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	// check deployment status
+	return wait.Poll(1*time.Second, genericflags.TimeOut(), func() (done bool, err error) {
+		deploy, err := d.kubeClient.AppsV1().Deployments(d.config.Namespace).Get(
+			ctx, constants.ControlPlaneName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if helpers.NumOfUnavailablePod(deploy) > 0 {
+			return false, nil
+		}
+
+		return true, nil
+	})
+}
+
+// applier returns the applier of the hosting cluster, the objects that are not supported by the typed
+// clients, e.g. the ingress, the route and the persistent volume claim, are applied with the
+// server-side apply.
+func (d *controlPlaneDeployer) applier() *resource.Applier {
+	return &resource.Applier{KubeClient: d.kubeClient, DynamicClient: d.dynamicClient}
+}
+
+// inventory returns the inventory of the connector, it's in the namespace of the connector.
+func (d *controlPlaneDeployer) inventory() *resource.Inventory {
+	return &resource.Inventory{Install: connectorInstall, Namespace: d.config.Namespace}
+}
+
+func mustCreateObjects(files []string, config interface{}) []runtime.Object {
+	objects := []runtime.Object{}
+	for _, file := range files {
+		template, err := manifestFiles.ReadFile(file)
+		if err != nil {
+			// this should not happen, if happened, panic here
+			panic(err)
+		}
+
+		objects = append(objects, resource.MustCreateObjectFromTemplate(file, template, config))
+	}
+
+	return objects
+}
+
+func reverse(objects []runtime.Object) []runtime.Object {
+	reversed := make([]runtime.Object, 0, len(objects))
+	for i := len(objects) - 1; i >= 0; i-- {
+		reversed = append(reversed, objects[i])
+	}
+
+	return reversed
+}
+
+// waitForNamespaceDeleted waits until the namespace is finalized and removed.
+func waitForNamespaceDeleted(ctx context.Context, kubeClient kubernetes.Interface, namespace string) error {
+	return wait.PollImmediate(1*time.Second, genericflags.TimeOut(), func() (bool, error) {
+		_, err := kubeClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		return false, nil
+	})
+}
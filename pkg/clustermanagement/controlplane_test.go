@@ -0,0 +1,100 @@
+package clustermanagement
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/resource"
+)
+
+func TestEnsureControlPlaneReconnect(t *testing.T) {
+	ctx := context.TODO()
+	replicas := int32(1)
+	kubeClient := fake.NewSimpleClientset(
+		newControlPlaneService(corev1.ServiceTypeLoadBalancer, 0, corev1.LoadBalancerIngress{Hostname: "cp.example.com"}),
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xcm", Name: constants.ControlPlaneName},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xcm", Name: constants.ControlPlaneKubeconfigSecretName},
+			Data:       map[string][]byte{"kubeconfig": []byte("kubeconfig")},
+		},
+	)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+
+	connect := func(rbacMode, connectorImage string, prune bool) {
+		d := &controlPlaneDeployer{
+			kubeClient:    kubeClient,
+			dynamicClient: dynamicClient,
+			config: &ControlPlaneConfig{
+				Namespace:      "xcm",
+				ServiceType:    corev1.ServiceTypeLoadBalancer,
+				ConnectorImage: connectorImage,
+				Replicas:       1,
+				RBACMode:       rbacMode,
+			},
+			rbac:  RBACOptions{RBACMode: rbacMode},
+			prune: prune,
+			hostname: func(ingress corev1.LoadBalancerIngress) string {
+				return ingress.Hostname
+			},
+		}
+
+		if err := d.ensureControlPlane(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		objects := append(d.exposeObjects(), d.controlPlaneObjects()...)
+		if err := recordInventory(ctx, io.Discard, d.applier(), d.inventory(), d.prune, objects); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the existing install is reconnected with the dedicated roles and a new connector image
+	connect(RBACModeAdmin, "connector:v1", false)
+	connect(RBACModeMinimal, "connector:v2", true)
+
+	deploy, err := kubeClient.AppsV1().Deployments("xcm").Get(ctx, constants.ControlPlaneName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image := deploy.Spec.Template.Spec.Containers[1].Image; image != "connector:v2" {
+		t.Errorf("expected the deployment is applied again, but got the connector image %q", image)
+	}
+	if _, err := kubeClient.RbacV1().Roles("xcm").Get(ctx, constants.ControlPlaneName, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the role is applied, but got %v", err)
+	}
+	binding, err := kubeClient.RbacV1().ClusterRoleBindings().Get(ctx, constants.ControlPlaneName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if binding.RoleRef.Name != constants.ControlPlaneName {
+		t.Errorf("expected the binding of the dedicated cluster role, but got %v", binding.RoleRef)
+	}
+
+	// the applied objects are recorded, so that they can be pruned later
+	refs, err := (&resource.Inventory{Install: connectorInstall, Namespace: "xcm"}).Load(ctx,
+		&resource.Applier{KubeClient: kubeClient, DynamicClient: dynamicClient})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recorded := false
+	for _, ref := range refs {
+		if ref.Kind == "Role" && ref.Name == constants.ControlPlaneName {
+			recorded = true
+		}
+	}
+	if !recorded {
+		t.Errorf("expected the role is recorded, but got %v", refs)
+	}
+}
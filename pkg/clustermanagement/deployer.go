@@ -0,0 +1,198 @@
+package clustermanagement
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/skeeey/xcm-cli/pkg/configs"
+)
+
+// DefaultProvider is the provider that is used when no provider is specified.
+const DefaultProvider = "eks"
+
+// Deployer deploys the xCM connector on a hosting cluster, the connector hosts a control plane that
+// the other clusters are relayed to.
+type Deployer interface {
+	// Connect deploys the xCM connector on the hosting cluster and connects the cluster to xCM.
+	Connect(ctx context.Context) error
+
+	// Disconnect removes the xCM connector from the hosting cluster.
+	Disconnect(ctx context.Context) error
+
+	// Connected checks if the xCM connector is deployed on the hosting cluster.
+	Connected(ctx context.Context) (bool, error)
+
+	// Status returns the status of each component of the xCM connector.
+	Status(ctx context.Context) ([]ComponentStatus, error)
+
+	// GetControlPlaneID returns the ID of the control plane.
+	GetControlPlaneID() string
+}
+
+// The optional capabilities of a deployer, the commands check if the deployer of a provider supports
+// them, see Preflighter too.
+
+// Renderer renders the objects that are applied to connect or relay a cluster without changing it.
+type Renderer interface {
+	// Render returns the ordered objects that are applied to the cluster.
+	Render(ctx context.Context) (*Manifests, error)
+
+	// DryRun prints or validates the objects that are applied to the cluster with the given dry-run
+	// strategy.
+	DryRun(ctx context.Context, w io.Writer, strategy string) error
+}
+
+// Upgrader updates the images of the xCM components and waits until they are rolled out, the
+// components are rolled back if the rollout fails.
+type Upgrader interface {
+	Upgrade(ctx context.Context, w io.Writer) error
+}
+
+// CertificateRotator regenerates the serving certificate of the control plane and the client
+// certificate of its admin kubeconfig, and restarts the control plane.
+type CertificateRotator interface {
+	RotateCertificates(ctx context.Context, w io.Writer) error
+}
+
+// ComponentStatus is the status of a component of the xCM connector.
+type ComponentStatus struct {
+	Name    string
+	Healthy bool
+	Reason  string
+}
+
+// PrintStatuses prints a checklist of the components, an error is returned if any component is
+// unhealthy, so that the command exits with a non-zero code.
+func PrintStatuses(w io.Writer, statuses []ComponentStatus) error {
+	unhealthy := 0
+	for _, status := range statuses {
+		mark := "[OK]  "
+		if !status.Healthy {
+			mark = "[FAIL]"
+			unhealthy++
+		}
+
+		if status.Reason == "" {
+			fmt.Fprintf(w, "%s %s\n", mark, status.Name)
+			continue
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", mark, status.Name, status.Reason)
+	}
+
+	if unhealthy > 0 {
+		return fmt.Errorf("%d of %d component(s) are unhealthy", unhealthy, len(statuses))
+	}
+
+	return nil
+}
+
+// DeployerOptions are the options to build a deployer.
+type DeployerOptions struct {
+	KubeconfigPath string
+	Namespace      string
+	XCMServer      string
+
+	// Prune deletes the objects of the connector that were applied by a previous connect but are no
+	// longer rendered.
+	Prune bool
+
+	// ImageOptions override the images of the control plane and the connector, the agent image is
+	// ignored.
+	ImageOptions
+
+	// ExposeOptions decide how the control plane is exposed.
+	ExposeOptions
+
+	// StorageOptions decide where the control plane data is stored and the number of its replicas.
+	StorageOptions
+
+	// RBACOptions decide the permissions of the control plane.
+	RBACOptions
+
+	// CAOptions decide the CA that the certificates of the control plane are signed by.
+	CAOptions
+}
+
+// Validate checks the image, the exposure, the storage, the rbac and the CA options.
+func (o *DeployerOptions) Validate() error {
+	if err := o.ImageOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.ExposeOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.StorageOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.RBACOptions.Validate(); err != nil {
+		return err
+	}
+
+	return o.CAOptions.Validate()
+}
+
+// DeployerFactory builds a deployer with the given options.
+type DeployerFactory func(opts *DeployerOptions) (Deployer, error)
+
+var (
+	providersLock sync.RWMutex
+	providers     = map[string]DeployerFactory{}
+)
+
+// RegisterProvider registers a deployer factory with the provider name, it panics if the provider is
+// already registered.
+func RegisterProvider(name string, factory DeployerFactory) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("provider %q is already registered", name))
+	}
+
+	providers[name] = factory
+}
+
+// Providers returns the names of the registered providers.
+func Providers() []string {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+
+	names := []string{}
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ContextProvider returns the provider that the connector of the given control plane context was
+// deployed with, the default provider is returned if the context is nil or was recorded by the
+// previous versions.
+func ContextProvider(context *configs.ControlPlaneContext) string {
+	if context == nil || context.Provider == "" {
+		return DefaultProvider
+	}
+	return context.Provider
+}
+
+// NewDeployer builds a deployer with the registered factory of the given provider.
+func NewDeployer(provider string, opts *DeployerOptions) (Deployer, error) {
+	providersLock.RLock()
+	factory, ok := providers[provider]
+	providersLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider %q, the supported providers are %s",
+			provider, strings.Join(Providers(), ", "))
+	}
+
+	return factory(opts)
+}
@@ -0,0 +1,106 @@
+package clustermanagement
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/skeeey/xcm-cli/pkg/configs"
+)
+
+func TestProviders(t *testing.T) {
+	names := map[string]bool{}
+	for _, name := range Providers() {
+		names[name] = true
+	}
+	if !names["eks"] || !names["kubernetes"] {
+		t.Errorf("expected the eks and kubernetes providers, but got %v", Providers())
+	}
+
+	if _, err := NewDeployer("gke", &DeployerOptions{}); err == nil {
+		t.Errorf("expected error of an unsupported provider")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic of a duplicate provider")
+		}
+	}()
+	RegisterProvider("kubernetes", func(opts *DeployerOptions) (Deployer, error) { return nil, nil })
+}
+
+func TestContextProvider(t *testing.T) {
+	cases := []struct {
+		name     string
+		context  *configs.ControlPlaneContext
+		expected string
+	}{
+		{name: "no context", expected: DefaultProvider},
+		{name: "recorded by previous versions", context: &configs.ControlPlaneContext{ID: "cp1"}, expected: DefaultProvider},
+		{name: "recorded", context: &configs.ControlPlaneContext{ID: "cp1", Provider: "kubernetes"}, expected: "kubernetes"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if provider := ContextProvider(c.context); provider != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, provider)
+			}
+		})
+	}
+}
+
+func TestProviderHostname(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := clientcmd.WriteToFile(clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"hosting": {Server: "https://127.0.0.1:1"}},
+		AuthInfos:      map[string]*clientcmdapi.AuthInfo{"hosting": {Token: "token"}},
+		Contexts:       map[string]*clientcmdapi.Context{"hosting": {Cluster: "hosting", AuthInfo: "hosting"}},
+		CurrentContext: "hosting",
+	}, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		provider string
+		expected string
+	}{
+		// the kubernetes load balancers may be published with an IP only, e.g. MetalLB
+		{provider: "kubernetes", expected: "198.51.100.1"},
+		// the AWS load balancers are always published with a hostname
+		{provider: "eks", expected: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.provider, func(t *testing.T) {
+			deployer, err := NewDeployer(c.provider, &DeployerOptions{KubeconfigPath: kubeconfig, Namespace: "xcm"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var d *controlPlaneDeployer
+			switch deployer := deployer.(type) {
+			case *KubernetesDeployer:
+				d = deployer.controlPlaneDeployer
+			case *EKSDeployer:
+				d = deployer.controlPlaneDeployer
+			default:
+				t.Fatalf("unexpected deployer %T", deployer)
+			}
+			d.kubeClient = fake.NewSimpleClientset(newControlPlaneService(corev1.ServiceTypeLoadBalancer, 0,
+				corev1.LoadBalancerIngress{IP: "198.51.100.1"}))
+
+			hostname, err := d.resolveHostname(context.TODO())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hostname != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, hostname)
+			}
+		})
+	}
+}
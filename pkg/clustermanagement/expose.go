@@ -0,0 +1,281 @@
+package clustermanagement
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+// The modes to expose the control plane.
+const (
+	// ExposeLoadBalancer exposes the control plane with a LoadBalancer service, the host is published
+	// by the load balancer with a hostname or an IP.
+	ExposeLoadBalancer = "loadbalancer"
+
+	// ExposeNodePort exposes the control plane with a NodePort service, the host is the external
+	// hostname or the address of a node.
+	ExposeNodePort = "nodeport"
+
+	// ExposeIngress exposes the control plane with an ingress, the ingress controller must support the
+	// TLS passthrough, e.g. the ingress-nginx with '--enable-ssl-passthrough'.
+	ExposeIngress = "ingress"
+
+	// ExposeRoute exposes the control plane with an OpenShift passthrough route, the host is assigned
+	// by the router unless the external hostname is specified.
+	ExposeRoute = "route"
+
+	// ExposeExternal doesn't expose the control plane, the control plane is reached with the external
+	// hostname, e.g. 'localhost:9443' with 'kubectl port-forward svc/multicluster-controlplane 9443:443'.
+	ExposeExternal = "external"
+)
+
+const (
+	ingressFile = "manifests/connector/ingress.yaml"
+	routeFile   = "manifests/connector/route.yaml"
+)
+
+var routeGVR = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+
+// ExposeOptions are the options to expose the control plane.
+type ExposeOptions struct {
+	Expose string
+
+	// ExternalHostname is the host that the control plane is reached with, it takes precedence over
+	// the host that is resolved from the exposure.
+	ExternalHostname string
+}
+
+// AddExposeFlags adds the flags of the exposure of the control plane to the given set of command line
+// flags.
+func AddExposeFlags(flags *pflag.FlagSet, opts *ExposeOptions) {
+	flags.StringVar(
+		&opts.Expose,
+		"expose",
+		ExposeLoadBalancer,
+		fmt.Sprintf("How the control plane is exposed. One of: %s.", strings.Join(exposeModes, "|")),
+	)
+
+	flags.StringVar(
+		&opts.ExternalHostname,
+		"external-hostname",
+		"",
+		"The host that the control plane is reached with, e.g. a DNS name of the load balancer. "+
+			"It is required by the 'ingress' and 'external' exposures, a port can be given with the "+
+			"'nodeport' and 'external' exposures, e.g. 'localhost:9443'.",
+	)
+}
+
+var exposeModes = []string{ExposeLoadBalancer, ExposeNodePort, ExposeIngress, ExposeRoute, ExposeExternal}
+
+// ContextExposeOptions returns the options that the control plane of the given context was exposed
+// with, the zero options are returned if the context is nil.
+func ContextExposeOptions(context *configs.ControlPlaneContext) ExposeOptions {
+	if context == nil {
+		return ExposeOptions{}
+	}
+	return ExposeOptions{Expose: context.Expose, ExternalHostname: context.ExternalHostname}
+}
+
+// Validate checks the exposure and its external hostname.
+func (o *ExposeOptions) Validate() error {
+	switch o.mode() {
+	case ExposeLoadBalancer, ExposeNodePort:
+	case ExposeRoute:
+		if strings.Contains(o.ExternalHostname, ":") {
+			return fmt.Errorf("the external hostname %q of the %s exposure cannot have a port",
+				o.ExternalHostname, ExposeRoute)
+		}
+	case ExposeIngress:
+		if o.ExternalHostname == "" {
+			return fmt.Errorf("the external hostname is required by the %s exposure", ExposeIngress)
+		}
+		if strings.Contains(o.ExternalHostname, ":") {
+			return fmt.Errorf("the external hostname %q of the %s exposure cannot have a port",
+				o.ExternalHostname, ExposeIngress)
+		}
+	case ExposeExternal:
+		if o.ExternalHostname == "" {
+			return fmt.Errorf("the external hostname is required by the %s exposure", ExposeExternal)
+		}
+	default:
+		return fmt.Errorf("unsupported exposure %q, the supported exposures are %s",
+			o.Expose, strings.Join(exposeModes, ", "))
+	}
+
+	return nil
+}
+
+// mode returns the exposure, the load balancer is the default.
+func (o *ExposeOptions) mode() string {
+	if o.Expose == "" {
+		return ExposeLoadBalancer
+	}
+	return o.Expose
+}
+
+// serviceType returns the type of the control plane service, the service of the ingress, the route
+// and the external exposures is only reachable in the cluster.
+func (o *ExposeOptions) serviceType() corev1.ServiceType {
+	switch o.mode() {
+	case ExposeLoadBalancer:
+		return corev1.ServiceTypeLoadBalancer
+	case ExposeNodePort:
+		return corev1.ServiceTypeNodePort
+	default:
+		return corev1.ServiceTypeClusterIP
+	}
+}
+
+// exposeFiles returns the manifests of the exposure besides the control plane service.
+func (o *ExposeOptions) exposeFiles() []string {
+	switch o.mode() {
+	case ExposeIngress:
+		return []string{ingressFile}
+	case ExposeRoute:
+		return []string{routeFile}
+	default:
+		return nil
+	}
+}
+
+// resolveHostname returns the host of the control plane, it's empty if the exposure is not ready.
+func (d *controlPlaneDeployer) resolveHostname(ctx context.Context) (string, error) {
+	switch d.expose.mode() {
+	case ExposeLoadBalancer:
+		if d.expose.ExternalHostname != "" {
+			return d.expose.ExternalHostname, nil
+		}
+		return d.loadBalancerHostname(ctx)
+	case ExposeNodePort:
+		return d.nodePortHostname(ctx)
+	case ExposeRoute:
+		if d.expose.ExternalHostname != "" {
+			return d.expose.ExternalHostname, nil
+		}
+		return d.routeHostname(ctx)
+	default:
+		return d.expose.ExternalHostname, nil
+	}
+}
+
+// loadBalancerHostname returns the host of the control plane from the load balancer ingress of the
+// control plane service, it's empty if the ingress is not ready.
+func (d *controlPlaneDeployer) loadBalancerHostname(ctx context.Context) (string, error) {
+	svc, err := d.kubeClient.CoreV1().Services(d.config.Namespace).Get(ctx, constants.ControlPlaneName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if hostname := d.hostname(ingress); hostname != "" {
+			return hostname, nil
+		}
+	}
+
+	return "", nil
+}
+
+// nodePortHostname returns the host and the node port of the control plane service, the host is the
+// external hostname, or the external IP of a node, or the internal IP of a node if no node has an
+// external IP. It's empty if the node port is not allocated.
+func (d *controlPlaneDeployer) nodePortHostname(ctx context.Context) (string, error) {
+	if _, _, err := net.SplitHostPort(d.expose.ExternalHostname); err == nil {
+		return d.expose.ExternalHostname, nil
+	}
+
+	svc, err := d.kubeClient.CoreV1().Services(d.config.Namespace).Get(ctx, constants.ControlPlaneName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if len(svc.Spec.Ports) == 0 || svc.Spec.Ports[0].NodePort == 0 {
+		return "", nil
+	}
+	port := fmt.Sprintf("%d", svc.Spec.Ports[0].NodePort)
+
+	if d.expose.ExternalHostname != "" {
+		return net.JoinHostPort(d.expose.ExternalHostname, port), nil
+	}
+
+	nodes, err := d.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	for _, addressType := range []corev1.NodeAddressType{corev1.NodeExternalIP, corev1.NodeInternalIP} {
+		for _, node := range nodes.Items {
+			for _, address := range node.Status.Addresses {
+				if address.Type == addressType && address.Address != "" {
+					return net.JoinHostPort(address.Address, port), nil
+				}
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// routeHostname returns the host of the control plane route, the router assigns the host when the
+// route is admitted. It's empty if the route is not found.
+func (d *controlPlaneDeployer) routeHostname(ctx context.Context) (string, error) {
+	route, err := d.dynamicClient.Resource(routeGVR).Namespace(d.config.Namespace).Get(
+		ctx, constants.ControlPlaneName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	host, _, err := unstructured.NestedString(route.Object, "spec", "host")
+	if err != nil {
+		return "", err
+	}
+
+	return host, nil
+}
+
+// checkExposure checks if the control plane is reachable with the resolved host.
+func (d *controlPlaneDeployer) checkExposure(ctx context.Context) (ComponentStatus, error) {
+	status := ComponentStatus{Name: fmt.Sprintf("%s exposure", d.expose.mode())}
+
+	_, err := d.kubeClient.CoreV1().Services(d.config.Namespace).Get(ctx, constants.ControlPlaneName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		status.Reason = fmt.Sprintf("the service %s/%s is not found", d.config.Namespace, constants.ControlPlaneName)
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+
+	hostname, err := d.resolveHostname(ctx)
+	if err != nil {
+		return status, err
+	}
+
+	if hostname == "" {
+		status.Reason = "the host of the control plane is not ready"
+		return status, nil
+	}
+
+	status.Healthy = true
+	status.Reason = hostname
+	return status, nil
+}
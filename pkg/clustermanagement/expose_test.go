@@ -0,0 +1,226 @@
+package clustermanagement
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+func TestValidateExpose(t *testing.T) {
+	cases := []struct {
+		name  string
+		opts  ExposeOptions
+		valid bool
+	}{
+		{name: "default", valid: true},
+		{name: "nodeport", opts: ExposeOptions{Expose: ExposeNodePort}, valid: true},
+		{name: "route with assigned host", opts: ExposeOptions{Expose: ExposeRoute}, valid: true},
+		{name: "ingress without host", opts: ExposeOptions{Expose: ExposeIngress}},
+		{name: "ingress with port", opts: ExposeOptions{Expose: ExposeIngress, ExternalHostname: "cp.example.com:443"}},
+		{name: "external", opts: ExposeOptions{Expose: ExposeExternal, ExternalHostname: "localhost:9443"}, valid: true},
+		{name: "external without host", opts: ExposeOptions{Expose: ExposeExternal}},
+		{name: "unsupported", opts: ExposeOptions{Expose: "gateway"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.opts.Validate()
+			if c.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !c.valid && err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestContextExposeOptions(t *testing.T) {
+	if opts := ContextExposeOptions(nil); opts.mode() != ExposeLoadBalancer {
+		t.Errorf("expected the load balancer exposure without context, but got %q", opts.mode())
+	}
+
+	opts := ContextExposeOptions(&configs.ControlPlaneContext{ID: "cp1", Expose: ExposeIngress, ExternalHostname: "cp.example.com"})
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.mode() != ExposeIngress || opts.ExternalHostname != "cp.example.com" {
+		t.Errorf("unexpected exposure %q with host %q", opts.mode(), opts.ExternalHostname)
+	}
+}
+
+func newControlPlaneService(serviceType corev1.ServiceType, nodePort int32, ingress ...corev1.LoadBalancerIngress) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: constants.ControlPlaneName, Namespace: "xcm"},
+		Spec: corev1.ServiceSpec{
+			Type:  serviceType,
+			Ports: []corev1.ServicePort{{Name: "app", Port: 443, NodePort: nodePort}},
+		},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: ingress}},
+	}
+}
+
+func TestResolveHostname(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+			{Type: corev1.NodeExternalIP, Address: "203.0.113.1"},
+		}},
+	}
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "route.openshift.io/v1",
+		"kind":       "Route",
+		"metadata":   map[string]interface{}{"name": constants.ControlPlaneName, "namespace": "xcm"},
+		"spec":       map[string]interface{}{"host": "cp-xcm.apps.example.com"},
+	}}
+
+	cases := []struct {
+		name     string
+		expose   ExposeOptions
+		objects  []runtime.Object
+		expected string
+	}{
+		{
+			name:     "load balancer is not ready",
+			objects:  []runtime.Object{newControlPlaneService(corev1.ServiceTypeLoadBalancer, 0)},
+			expected: "",
+		},
+		{
+			name: "load balancer with ip",
+			objects: []runtime.Object{newControlPlaneService(corev1.ServiceTypeLoadBalancer, 0,
+				corev1.LoadBalancerIngress{IP: "198.51.100.1"})},
+			expected: "198.51.100.1",
+		},
+		{
+			name:     "load balancer with external hostname",
+			expose:   ExposeOptions{ExternalHostname: "cp.example.com"},
+			objects:  []runtime.Object{newControlPlaneService(corev1.ServiceTypeLoadBalancer, 0)},
+			expected: "cp.example.com",
+		},
+		{
+			name:     "node port with node address",
+			expose:   ExposeOptions{Expose: ExposeNodePort},
+			objects:  []runtime.Object{newControlPlaneService(corev1.ServiceTypeNodePort, 30443), node},
+			expected: "203.0.113.1:30443",
+		},
+		{
+			name:     "node port with external hostname",
+			expose:   ExposeOptions{Expose: ExposeNodePort, ExternalHostname: "cp.example.com"},
+			objects:  []runtime.Object{newControlPlaneService(corev1.ServiceTypeNodePort, 30443)},
+			expected: "cp.example.com:30443",
+		},
+		{
+			name:     "node port is not allocated",
+			expose:   ExposeOptions{Expose: ExposeNodePort},
+			objects:  []runtime.Object{newControlPlaneService(corev1.ServiceTypeNodePort, 0), node},
+			expected: "",
+		},
+		{
+			name:     "ingress",
+			expose:   ExposeOptions{Expose: ExposeIngress, ExternalHostname: "cp.example.com"},
+			expected: "cp.example.com",
+		},
+		{
+			name:     "external",
+			expose:   ExposeOptions{Expose: ExposeExternal, ExternalHostname: "localhost:9443"},
+			expected: "localhost:9443",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := &controlPlaneDeployer{
+				kubeClient:    fake.NewSimpleClientset(c.objects...),
+				dynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+				config:        &ControlPlaneConfig{Namespace: "xcm"},
+				expose:        c.expose,
+				hostname: func(ingress corev1.LoadBalancerIngress) string {
+					if ingress.Hostname != "" {
+						return ingress.Hostname
+					}
+					return ingress.IP
+				},
+			}
+
+			hostname, err := d.resolveHostname(context.TODO())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hostname != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, hostname)
+			}
+		})
+	}
+
+	t.Run("route", func(t *testing.T) {
+		d := &controlPlaneDeployer{
+			kubeClient:    fake.NewSimpleClientset(),
+			dynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), route),
+			config:        &ControlPlaneConfig{Namespace: "xcm"},
+			expose:        ExposeOptions{Expose: ExposeRoute},
+		}
+
+		hostname, err := d.resolveHostname(context.TODO())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if hostname != "cp-xcm.apps.example.com" {
+			t.Errorf("expected the host assigned by the router, but got %q", hostname)
+		}
+	})
+}
+
+func TestExposeObjects(t *testing.T) {
+	cases := []struct {
+		expose   ExposeOptions
+		expected []string
+	}{
+		{
+			expose:   ExposeOptions{Expose: ExposeLoadBalancer},
+			expected: []string{"type: LoadBalancer"},
+		},
+		{
+			expose:   ExposeOptions{Expose: ExposeIngress, ExternalHostname: "cp.example.com"},
+			expected: []string{"type: ClusterIP", "kind: Ingress", "host: cp.example.com", "ssl-passthrough"},
+		},
+		{
+			expose:   ExposeOptions{Expose: ExposeRoute},
+			expected: []string{"type: ClusterIP", "kind: Route", "termination: passthrough"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expose.Expose, func(t *testing.T) {
+			d := &controlPlaneDeployer{
+				config: &ControlPlaneConfig{
+					Namespace:        "xcm",
+					ServiceType:      c.expose.serviceType(),
+					ExternalHostname: c.expose.ExternalHostname,
+				},
+				expose: c.expose,
+			}
+
+			out := &bytes.Buffer{}
+			if err := (&Manifests{Objects: d.exposeObjects()}).WriteYAML(out); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, s := range c.expected {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("expected %q is rendered, but got\n%s", s, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package clustermanagement
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+// ImageOptions override the images of the deployed components.
+type ImageOptions struct {
+	ControlPlaneImage string
+	ConnectorImage    string
+	AgentImage        string
+
+	// ImageRegistry replaces the registry of the images, e.g. with the registry 'mirror.example.com/ocm',
+	// the image 'quay.io/open-cluster-management/multicluster-controlplane' is pulled from
+	// 'mirror.example.com/ocm/open-cluster-management/multicluster-controlplane'.
+	ImageRegistry string
+
+	// ImagePullSecret is an existing docker config secret '<namespace>/<name>' on the cluster, it is
+	// copied into the namespace of the deployed components.
+	ImagePullSecret string
+
+	// Version replaces the tag of the default images, the images that are given explicitly are kept.
+	Version string
+}
+
+// AddImageFlags adds the flags of the image registry and the image pull secret to the given set of
+// command line flags.
+func AddImageFlags(flags *pflag.FlagSet, opts *ImageOptions) {
+	flags.StringVar(
+		&opts.ImageRegistry,
+		"image-registry",
+		"",
+		"The registry that the images are pulled from, it replaces the registry of the images, "+
+			"e.g. 'mirror.example.com/ocm'.",
+	)
+
+	flags.StringVar(
+		&opts.ImagePullSecret,
+		"image-pull-secret",
+		"",
+		"An existing docker config secret '<namespace>/<name>' on the cluster to pull the images, "+
+			"it is copied into the namespace of the deployed components.",
+	)
+}
+
+// AddControlPlaneImageFlags adds the flags of the control plane image and the xCM connector image to
+// the given set of command line flags.
+func AddControlPlaneImageFlags(flags *pflag.FlagSet, opts *ImageOptions) {
+	flags.StringVar(
+		&opts.ControlPlaneImage,
+		"controlplane-image",
+		"",
+		fmt.Sprintf("The image of the control plane. The default value is %s.", constants.DefaultControlPlaneImage),
+	)
+
+	flags.StringVar(
+		&opts.ConnectorImage,
+		"connector-image",
+		"",
+		fmt.Sprintf("The image of the xCM connector. The default value is %s.", constants.DefaultConnectorImage),
+	)
+}
+
+// AddAgentImageFlags adds the flag of the agent image to the given set of command line flags.
+func AddAgentImageFlags(flags *pflag.FlagSet, opts *ImageOptions) {
+	flags.StringVar(
+		&opts.AgentImage,
+		"agent-image",
+		"",
+		fmt.Sprintf("The image of the agent. The default value is %s.", constants.DefaultAgentImage),
+	)
+}
+
+// ContextImageOptions returns the image options with the images that are recorded in a control plane
+// context, the images and the image registry that are given in the options take precedence.
+func ContextImageOptions(images *configs.Images, opts ImageOptions) ImageOptions {
+	if images == nil {
+		return opts
+	}
+
+	for _, image := range []struct {
+		recorded string
+		given    *string
+	}{
+		{recorded: images.ControlPlane, given: &opts.ControlPlaneImage},
+		{recorded: images.Connector, given: &opts.ConnectorImage},
+		{recorded: images.Agent, given: &opts.AgentImage},
+		{recorded: images.Registry, given: &opts.ImageRegistry},
+	} {
+		if *image.given == "" {
+			*image.given = image.recorded
+		}
+	}
+
+	return opts
+}
+
+// ContextImages returns the images and the image registry that are recorded in a control plane
+// context, nil is returned if none of them is given.
+func (o *ImageOptions) ContextImages() *configs.Images {
+	images := &configs.Images{
+		ControlPlane: o.ControlPlaneImage,
+		Connector:    o.ConnectorImage,
+		Agent:        o.AgentImage,
+		Registry:     o.ImageRegistry,
+	}
+	if *images == (configs.Images{}) {
+		return nil
+	}
+	return images
+}
+
+// Validate checks the format of the image pull secret.
+func (o *ImageOptions) Validate() error {
+	if o.ImagePullSecret == "" {
+		return nil
+	}
+
+	if _, _, err := o.imagePullSecret(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// image returns the image of a component, the given image takes precedence over the default image,
+// and its registry is replaced with the image registry.
+func (o *ImageOptions) image(image, defaultImage string) string {
+	if image == "" {
+		image = defaultImage
+		if o.Version != "" {
+			image = withTag(image, o.Version)
+		}
+	}
+
+	if o.ImageRegistry == "" {
+		return image
+	}
+
+	path := image
+	if i := strings.IndexRune(image, '/'); i != -1 && imageRegistry(image) == image[:i] {
+		path = image[i+1:]
+	}
+
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(o.ImageRegistry, "/"), path)
+}
+
+// withTag replaces the tag of the image with the given tag.
+func withTag(image, tag string) string {
+	name := image
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name = image[:i]
+	}
+
+	return fmt.Sprintf("%s:%s", name, tag)
+}
+
+// imagePullSecret returns the namespace and the name of the image pull secret.
+func (o *ImageOptions) imagePullSecret() (string, string, error) {
+	namespace, name, ok := strings.Cut(o.ImagePullSecret, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("invalid image pull secret %q, the format is <namespace>/<name>", o.ImagePullSecret)
+	}
+
+	return namespace, name, nil
+}
+
+// imagePullSecretName returns the name of the image pull secret that is copied into the namespace of
+// the deployed components, it's empty if there is no image pull secret.
+func (o *ImageOptions) imagePullSecretName() string {
+	_, name, err := o.imagePullSecret()
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// loadImagePullSecret returns the docker config of the image pull secret, nil is returned if there is
+// no image pull secret.
+func (o *ImageOptions) loadImagePullSecret(ctx context.Context, kubeClient kubernetes.Interface) ([]byte, error) {
+	if o.ImagePullSecret == "" {
+		return nil, nil
+	}
+
+	namespace, name, err := o.imagePullSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the image pull secret %s: %v", o.ImagePullSecret, err)
+	}
+
+	data, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if secret.Type != corev1.SecretTypeDockerConfigJson || !ok {
+		return nil, fmt.Errorf("the image pull secret %s is not a %s secret",
+			o.ImagePullSecret, corev1.SecretTypeDockerConfigJson)
+	}
+
+	return data, nil
+}
+
+// checkImagePullSecret checks if the image pull secret can be copied.
+func checkImagePullSecret(ctx context.Context, kubeClient kubernetes.Interface, opts *ImageOptions) ComponentStatus {
+	status := ComponentStatus{Name: "image pull secret"}
+
+	if _, err := opts.loadImagePullSecret(ctx, kubeClient); err != nil {
+		status.Reason = err.Error()
+		return status
+	}
+
+	status.Healthy = true
+	status.Reason = opts.ImagePullSecret
+	return status
+}
@@ -0,0 +1,142 @@
+package clustermanagement
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+func TestImage(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     ImageOptions
+		image    string
+		expected string
+	}{
+		{
+			name:     "default",
+			expected: constants.DefaultControlPlaneImage,
+		},
+		{
+			name:     "override",
+			image:    "quay.io/open-cluster-management/multicluster-controlplane:v0.1.0",
+			expected: "quay.io/open-cluster-management/multicluster-controlplane:v0.1.0",
+		},
+		{
+			name:     "registry",
+			opts:     ImageOptions{ImageRegistry: "mirror.example.com/ocm/"},
+			expected: "mirror.example.com/ocm/open-cluster-management/multicluster-controlplane",
+		},
+		{
+			name:     "version",
+			opts:     ImageOptions{Version: "v0.2.0", ImageRegistry: "localhost:5000"},
+			expected: "localhost:5000/open-cluster-management/multicluster-controlplane:v0.2.0",
+		},
+		{
+			name:     "version is ignored with an explicit image",
+			opts:     ImageOptions{Version: "v0.2.0"},
+			image:    "quay.io/open-cluster-management/multicluster-controlplane:v0.1.0",
+			expected: "quay.io/open-cluster-management/multicluster-controlplane:v0.1.0",
+		},
+		{
+			name:     "registry of a docker hub image",
+			opts:     ImageOptions{ImageRegistry: "mirror.example.com"},
+			image:    "library/busybox",
+			expected: "mirror.example.com/library/busybox",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := c.opts.image(c.image, constants.DefaultControlPlaneImage); actual != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestImagePullSecret(t *testing.T) {
+	for _, invalid := range []string{"secret", "/secret", "ns/", "ns/a/b"} {
+		if err := (&ImageOptions{ImagePullSecret: invalid}).Validate(); err == nil {
+			t.Errorf("expected error with %q", invalid)
+		}
+	}
+
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "mirror"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "opaque"},
+		},
+	)
+
+	if status := checkImagePullSecret(context.TODO(), kubeClient, &ImageOptions{ImagePullSecret: "default/opaque"}); status.Healthy {
+		t.Errorf("expected the opaque secret is rejected")
+	}
+
+	d := &SpokeDeployer{
+		kubeClient: kubeClient,
+		images:     ImageOptions{ImagePullSecret: "default/mirror"},
+		agentImage: constants.DefaultAgentImage,
+	}
+	if err := d.loadImagePullSecret(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	objects := d.agentObjects()
+	secretIndex, deploymentIndex := -1, -1
+	for i, obj := range objects {
+		switch o := obj.(type) {
+		case *corev1.Secret:
+			if o.Name == "mirror" {
+				secretIndex = i
+				if o.Namespace != constants.DefaultControlPlaneAgentNamespace ||
+					string(o.Data[corev1.DockerConfigJsonKey]) != `{"auths":{}}` {
+					t.Errorf("unexpected image pull secret %v", o)
+				}
+			}
+		case *appsv1.Deployment:
+			deploymentIndex = i
+			pullSecrets := o.Spec.Template.Spec.ImagePullSecrets
+			if len(pullSecrets) != 1 || pullSecrets[0].Name != "mirror" {
+				t.Errorf("unexpected image pull secrets %v", pullSecrets)
+			}
+		}
+	}
+	if secretIndex == -1 || secretIndex > deploymentIndex {
+		t.Errorf("expected the image pull secret is created before the deployment")
+	}
+}
+
+func TestContextImageOptions(t *testing.T) {
+	if images := (&ImageOptions{ImagePullSecret: "default/pull-secret"}).ContextImages(); images != nil {
+		t.Errorf("expected no images to record, but got %v", images)
+	}
+
+	recorded := (&ImageOptions{ConnectorImage: "mirror.example.com/connector:v1", ImageRegistry: "mirror.example.com"}).ContextImages()
+
+	// the recorded images are kept unless they're given again
+	opts := ContextImageOptions(recorded, ImageOptions{ControlPlaneImage: "controlplane:v2", Version: "v2"})
+	if opts.ControlPlaneImage != "controlplane:v2" || opts.ConnectorImage != "mirror.example.com/connector:v1" ||
+		opts.ImageRegistry != "mirror.example.com" || opts.Version != "v2" {
+		t.Errorf("unexpected image options %v", opts)
+	}
+
+	opts = ContextImageOptions(recorded, ImageOptions{ImageRegistry: "registry.example.com"})
+	if opts.ImageRegistry != "registry.example.com" {
+		t.Errorf("expected the given image registry takes precedence, but got %q", opts.ImageRegistry)
+	}
+
+	if opts := ContextImageOptions(nil, ImageOptions{Version: "v2"}); opts != (ImageOptions{Version: "v2"}) {
+		t.Errorf("unexpected image options %v", opts)
+	}
+}
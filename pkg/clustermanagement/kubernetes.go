@@ -0,0 +1,31 @@
+package clustermanagement
+
+func init() {
+	RegisterProvider("kubernetes", func(opts *DeployerOptions) (Deployer, error) {
+		return BuildKubernetesDeployer(opts)
+	})
+}
+
+// KubernetesDeployer deploys the xCM connector on a vanilla Kubernetes cluster, e.g. kind, OpenShift,
+// GKE or AKS, the control plane is exposed with a load balancer that is published with a hostname
+// or an IP. All of the cluster claims are detected.
+type KubernetesDeployer struct {
+	*controlPlaneDeployer
+}
+
+var (
+	_ Deployer           = &KubernetesDeployer{}
+	_ Preflighter        = &KubernetesDeployer{}
+	_ Renderer           = &KubernetesDeployer{}
+	_ Upgrader           = &KubernetesDeployer{}
+	_ CertificateRotator = &KubernetesDeployer{}
+)
+
+func BuildKubernetesDeployer(opts *DeployerOptions) (*KubernetesDeployer, error) {
+	deployer, err := newControlPlaneDeployer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KubernetesDeployer{controlPlaneDeployer: deployer}, nil
+}
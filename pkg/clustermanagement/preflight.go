@@ -0,0 +1,332 @@
+package clustermanagement
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+
+	"github.com/skeeey/xcm-cli/pkg/resource"
+)
+
+// applyVerbs are the verbs that are required to apply an object.
+var applyVerbs = []string{"get", "create", "update"}
+
+var clusterClaimResource = clusterv1alpha1.SchemeGroupVersion.WithResource("clusterclaims")
+
+// openshiftImageConfigResource is the image configuration of OpenShift, its registry sources
+// restrict the registries that the images can be pulled from.
+var openshiftImageConfigResource = schema.GroupVersionResource{
+	Group:    "config.openshift.io",
+	Version:  "v1",
+	Resource: "images",
+}
+
+// Preflighter checks if the xCM components can be deployed on a cluster.
+type Preflighter interface {
+	Preflight(ctx context.Context) ([]ComponentStatus, error)
+}
+
+// RunPreflight runs the preflight checks before connecting or relaying a cluster, the checklist is
+// printed only if any check fails.
+func RunPreflight(ctx context.Context, w io.Writer, preflighter Preflighter) error {
+	fmt.Fprintln(w, "Run the preflight checks ...")
+	statuses, err := preflighter.Preflight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run the preflight checks: %v", err)
+	}
+
+	healthy := true
+	for _, status := range statuses {
+		if !status.Healthy {
+			healthy = false
+			break
+		}
+	}
+	if healthy {
+		return nil
+	}
+
+	if err := PrintStatuses(w, statuses); err != nil {
+		return fmt.Errorf("the preflight checks failed, %v", err)
+	}
+
+	return nil
+}
+
+// checkPermissions checks if current user is allowed to apply the objects and the cluster claims with
+// SelfSubjectAccessReview, the denied permissions of all the objects are reported at once.
+func checkPermissions(ctx context.Context, kubeClient kubernetes.Interface, objects []runtime.Object) ComponentStatus {
+	status := ComponentStatus{Name: "permissions"}
+
+	attributes := []authorizationv1.ResourceAttributes{}
+	for _, obj := range objects {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			status.Reason = err.Error()
+			return status
+		}
+
+		gvr := resource.GroupVersionResource(obj.GetObjectKind().GroupVersionKind())
+		for _, verb := range applyVerbs {
+			attributes = append(attributes, authorizationv1.ResourceAttributes{
+				Namespace: accessor.GetNamespace(),
+				Verb:      verb,
+				Group:     gvr.Group,
+				Version:   gvr.Version,
+				Resource:  gvr.Resource,
+				Name:      accessor.GetName(),
+			})
+		}
+	}
+
+	for _, verb := range applyVerbs {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{
+			Verb:     verb,
+			Group:    clusterClaimResource.Group,
+			Version:  clusterClaimResource.Version,
+			Resource: clusterClaimResource.Resource,
+		})
+	}
+
+	denied := []string{}
+	for _, attr := range attributes {
+		attr := attr
+		review, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx,
+			&authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attr},
+			}, metav1.CreateOptions{})
+		if err != nil {
+			status.Reason = fmt.Sprintf("failed to review the access: %v", err)
+			return status
+		}
+
+		if !review.Status.Allowed {
+			denied = append(denied, describeAttributes(attr))
+		}
+	}
+
+	if len(denied) > 0 {
+		status.Reason = fmt.Sprintf("current user cannot %s", strings.Join(denied, ", "))
+		return status
+	}
+
+	status.Healthy = true
+	return status
+}
+
+func describeAttributes(attr authorizationv1.ResourceAttributes) string {
+	groupResource := attr.Resource
+	if attr.Group != "" {
+		groupResource = fmt.Sprintf("%s.%s", attr.Resource, attr.Group)
+	}
+
+	name := attr.Name
+	if attr.Namespace != "" {
+		name = fmt.Sprintf("%s/%s", attr.Namespace, attr.Name)
+	}
+
+	if name == "" {
+		return fmt.Sprintf("%s %s", attr.Verb, groupResource)
+	}
+	return fmt.Sprintf("%s %s %s", attr.Verb, groupResource, name)
+}
+
+// checkClusterClaimCRD checks if the ClusterClaim API is served by the cluster.
+func checkClusterClaimCRD(kubeClient kubernetes.Interface) ComponentStatus {
+	status := ComponentStatus{Name: "cluster claim CRD"}
+
+	resources, err := kubeClient.Discovery().ServerResourcesForGroupVersion(clusterClaimResource.GroupVersion().String())
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		status.Reason = fmt.Sprintf("failed to discover %s: %v", clusterClaimResource.GroupVersion(), err)
+		return status
+	default:
+		for _, apiResource := range resources.APIResources {
+			if apiResource.Name == clusterClaimResource.Resource {
+				status.Healthy = true
+				return status
+			}
+		}
+	}
+
+	status.Reason = fmt.Sprintf("the CRD %s.%s is not found", clusterClaimResource.Resource, clusterClaimResource.Group)
+	return status
+}
+
+// checkNamespaces checks if the namespaces are not terminating, the objects cannot be created in a
+// terminating namespace.
+func checkNamespaces(ctx context.Context, kubeClient kubernetes.Interface, namespaces ...string) ComponentStatus {
+	status := ComponentStatus{Name: "namespaces"}
+
+	terminating := []string{}
+	for _, name := range namespaces {
+		ns, err := kubeClient.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+		case err != nil:
+			status.Reason = fmt.Sprintf("failed to get the namespace %s: %v", name, err)
+			return status
+		case ns.DeletionTimestamp != nil || ns.Status.Phase == corev1.NamespaceTerminating:
+			terminating = append(terminating, name)
+		}
+	}
+
+	if len(terminating) > 0 {
+		status.Reason = fmt.Sprintf("the namespace(s) %s are terminating, wait until they are deleted",
+			strings.Join(terminating, ", "))
+		return status
+	}
+
+	status.Healthy = true
+	return status
+}
+
+// checkLoadBalancer checks if the cluster can provision the LoadBalancer services, a cluster that
+// runs with a cloud provider or has provisioned a LoadBalancer service is considered to support it.
+func checkLoadBalancer(ctx context.Context, kubeClient kubernetes.Interface) ComponentStatus {
+	status := ComponentStatus{Name: "load balancer"}
+
+	nodes, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		status.Reason = fmt.Sprintf("failed to list the nodes: %v", err)
+		return status
+	}
+	for _, node := range nodes.Items {
+		if node.Spec.ProviderID != "" {
+			status.Healthy = true
+			return status
+		}
+	}
+
+	services, err := kubeClient.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		status.Reason = fmt.Sprintf("failed to list the services: %v", err)
+		return status
+	}
+	for _, svc := range services.Items {
+		if svc.Spec.Type == corev1.ServiceTypeLoadBalancer && len(svc.Status.LoadBalancer.Ingress) > 0 {
+			status.Healthy = true
+			return status
+		}
+	}
+
+	status.Reason = "neither a cloud provider nor a provisioned LoadBalancer service is found, " +
+		"the LoadBalancer service of the control plane may not be provisioned"
+	return status
+}
+
+// checkImages checks if the images of the deployments can be pulled with their pull policies and the
+// registry sources of the OpenShift image configuration.
+func checkImages(ctx context.Context, dynamicClient dynamic.Interface, objects []runtime.Object) ComponentStatus {
+	status := ComponentStatus{Name: "images"}
+
+	allowed, blocked, err := registrySources(ctx, dynamicClient)
+	if err != nil {
+		status.Reason = fmt.Sprintf("failed to get the image configuration: %v", err)
+		return status
+	}
+
+	problems := []string{}
+	for _, obj := range objects {
+		deploy, ok := obj.(*appsv1.Deployment)
+		if !ok {
+			continue
+		}
+
+		containers := []corev1.Container{}
+		containers = append(containers, deploy.Spec.Template.Spec.InitContainers...)
+		containers = append(containers, deploy.Spec.Template.Spec.Containers...)
+		for _, container := range containers {
+			registry := imageRegistry(container.Image)
+			switch {
+			case container.Image == "":
+				problems = append(problems, fmt.Sprintf("the image of container %s is empty", container.Name))
+			case container.ImagePullPolicy == corev1.PullNever:
+				problems = append(problems, fmt.Sprintf("the image %s is never pulled", container.Image))
+			case len(allowed) > 0 && !matchRegistry(registry, allowed):
+				problems = append(problems, fmt.Sprintf("the registry %s of image %s is not allowed", registry, container.Image))
+			case matchRegistry(registry, blocked):
+				problems = append(problems, fmt.Sprintf("the registry %s of image %s is blocked", registry, container.Image))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		status.Reason = strings.Join(problems, "; ")
+		return status
+	}
+
+	status.Healthy = true
+	return status
+}
+
+// registrySources returns the allowed and blocked registries of the OpenShift image configuration,
+// nothing is returned if the cluster isn't OpenShift.
+func registrySources(ctx context.Context, dynamicClient dynamic.Interface) ([]string, []string, error) {
+	config, err := dynamicClient.Resource(openshiftImageConfigResource).Get(ctx, "cluster", metav1.GetOptions{})
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allowed, _, err := unstructured.NestedStringSlice(config.Object, "spec", "registrySources", "allowedRegistries")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blocked, _, err := unstructured.NestedStringSlice(config.Object, "spec", "registrySources", "blockedRegistries")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return allowed, blocked, nil
+}
+
+// imageRegistry returns the registry of the image, the image without a registry is pulled from
+// docker.io.
+func imageRegistry(image string) string {
+	i := strings.IndexRune(image, '/')
+	if i == -1 {
+		return "docker.io"
+	}
+
+	registry := image[:i]
+	if !strings.ContainsAny(registry, ".:") && registry != "localhost" {
+		return "docker.io"
+	}
+
+	return registry
+}
+
+// matchRegistry checks if the registry matches one of the patterns, the pattern can start with a
+// wildcard of the subdomains, e.g. '*.example.com'.
+func matchRegistry(registry string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == registry {
+			return true
+		}
+
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(registry, pattern[1:]) {
+			return true
+		}
+	}
+
+	return false
+}
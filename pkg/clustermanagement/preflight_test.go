@@ -0,0 +1,148 @@
+package clustermanagement
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "selfsubjectaccessreviews",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			attr := review.Spec.ResourceAttributes
+			review.Status.Allowed = !(attr.Resource == "clusterrolebindings" && attr.Verb == "create")
+			return true, review, nil
+		})
+
+	objects := (&SpokeDeployer{agentImage: constants.DefaultAgentImage}).agentObjects()
+	status := checkPermissions(context.TODO(), kubeClient, objects)
+	if status.Healthy {
+		t.Fatalf("expected the permissions are denied")
+	}
+	if !strings.Contains(status.Reason, "create clusterrolebindings.rbac.authorization.k8s.io") ||
+		strings.Contains(status.Reason, "get ") {
+		t.Errorf("unexpected reason %q", status.Reason)
+	}
+}
+
+func TestCheckNamespaces(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "active"}},
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "terminating"},
+			Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating},
+		},
+	)
+
+	if status := checkNamespaces(context.TODO(), kubeClient, "active", "absent"); !status.Healthy {
+		t.Errorf("unexpected status %v", status)
+	}
+	if status := checkNamespaces(context.TODO(), kubeClient, "active", "terminating"); status.Healthy ||
+		!strings.Contains(status.Reason, "terminating") {
+		t.Errorf("unexpected status %v", status)
+	}
+}
+
+func TestCheckLoadBalancer(t *testing.T) {
+	if status := checkLoadBalancer(context.TODO(), fake.NewSimpleClientset()); status.Healthy {
+		t.Errorf("expected the load balancer is not supported")
+	}
+
+	kubeClient := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123"},
+	})
+	if status := checkLoadBalancer(context.TODO(), kubeClient); !status.Healthy {
+		t.Errorf("unexpected status %v", status)
+	}
+}
+
+func TestCheckImages(t *testing.T) {
+	objects := (&SpokeDeployer{agentImage: constants.DefaultAgentImage}).agentObjects()
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	if status := checkImages(context.TODO(), dynamicClient, objects); !status.Healthy {
+		t.Errorf("unexpected status %v", status)
+	}
+
+	imageConfig := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "config.openshift.io/v1",
+		"kind":       "Image",
+		"metadata":   map[string]interface{}{"name": "cluster"},
+		"spec": map[string]interface{}{
+			"registrySources": map[string]interface{}{
+				"allowedRegistries": []interface{}{"registry.redhat.io", "*.example.com"},
+			},
+		},
+	}}
+	dynamicClient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), imageConfig)
+	if status := checkImages(context.TODO(), dynamicClient, objects); status.Healthy ||
+		!strings.Contains(status.Reason, "the registry quay.io") {
+		t.Errorf("unexpected status %v", status)
+	}
+}
+
+func TestImageRegistry(t *testing.T) {
+	cases := map[string]string{
+		"busybox":             "docker.io",
+		"library/busybox:1.0": "docker.io",
+		"quay.io/open-cluster-management/multicluster-controlplane": "quay.io",
+		"localhost/agent": "localhost",
+		"registry.example.com:5000/agent@sha256:abc": "registry.example.com:5000",
+	}
+
+	for image, expected := range cases {
+		if actual := imageRegistry(image); actual != expected {
+			t.Errorf("expected registry %q of %q, but got %q", expected, image, actual)
+		}
+	}
+
+	if !matchRegistry("mirror.example.com", []string{"*.example.com"}) {
+		t.Errorf("expected the wildcard matches the subdomain")
+	}
+	if matchRegistry("example.com", []string{"*.example.com"}) {
+		t.Errorf("expected the wildcard doesn't match the domain")
+	}
+}
+
+type fakePreflighter []ComponentStatus
+
+func (p fakePreflighter) Preflight(ctx context.Context) ([]ComponentStatus, error) {
+	return p, nil
+}
+
+func TestRunPreflight(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := RunPreflight(context.TODO(), out, fakePreflighter{{Name: "permissions", Healthy: true}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "permissions") {
+		t.Errorf("expected the checklist isn't printed when the checks pass, but got %q", out.String())
+	}
+
+	out.Reset()
+	err := RunPreflight(context.TODO(), out, fakePreflighter{
+		{Name: "permissions", Reason: "denied"},
+		{Name: "images", Reason: "not allowed"},
+	})
+	if err == nil {
+		t.Errorf("expected error when the checks fail")
+	}
+	if count := strings.Count(out.String(), "[FAIL] permissions"); count != 1 {
+		t.Errorf("expected the checklist is printed once, but got %q", out.String())
+	}
+}
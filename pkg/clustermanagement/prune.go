@@ -0,0 +1,48 @@
+package clustermanagement
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/skeeey/xcm-cli/pkg/resource"
+)
+
+const (
+	// connectorInstall is the install of the objects of the xCM connector.
+	connectorInstall = "connector"
+
+	// agentInstall is the install of the objects of the agent.
+	agentInstall = "agent"
+)
+
+// recordInventory records the applied objects in the inventory of the install. If prune is true, the
+// objects that were recorded by a previous apply but are no longer rendered are deleted first, and the
+// inventory is replaced only if they are all pruned. Otherwise, the applied objects are added to the
+// inventory, so that the objects that are no longer rendered can be pruned later.
+func recordInventory(ctx context.Context, w io.Writer, applier *resource.Applier, inventory *resource.Inventory,
+	prune bool, objects []runtime.Object) error {
+	if !prune {
+		if err := inventory.Merge(ctx, applier, objects...); err != nil {
+			return fmt.Errorf("failed to record the objects of %s: %v", inventory.Install, err)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(w, "Prune the objects that are no longer rendered [%s] ...\n", inventory.Install)
+	pruned, err := inventory.Prune(ctx, w, applier, objects...)
+	if err != nil {
+		return fmt.Errorf("failed to prune the objects of %s: %v", inventory.Install, err)
+	}
+	if len(pruned) == 0 {
+		fmt.Fprintln(w, "No objects are pruned")
+	}
+
+	if err := inventory.Save(ctx, applier, objects...); err != nil {
+		return fmt.Errorf("failed to record the objects of %s: %v", inventory.Install, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package clustermanagement
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// The modes of the permissions of the control plane and the agent.
+const (
+	// RBACModeAdmin binds the service accounts to the cluster-admin cluster role.
+	RBACModeAdmin = "admin"
+
+	// RBACModeMinimal binds the service accounts to the dedicated cluster roles and roles that grant the
+	// verbs the control plane and the agent require, the manifest works can only apply the objects that
+	// are granted to the agent.
+	RBACModeMinimal = "minimal"
+)
+
+var rbacModes = []string{RBACModeAdmin, RBACModeMinimal}
+
+// RBACOptions are the options of the permissions of the control plane and the agent.
+type RBACOptions struct {
+	RBACMode string
+}
+
+// AddRBACFlags adds the flags of the permissions to the given set of command line flags.
+func AddRBACFlags(flags *pflag.FlagSet, opts *RBACOptions) {
+	flags.StringVar(
+		&opts.RBACMode,
+		"rbac-mode",
+		RBACModeAdmin,
+		fmt.Sprintf("The permissions of the xCM components on the cluster. One of: %s. "+
+			"If minimal, the components are bound to the dedicated roles instead of cluster-admin, "+
+			"and the manifest works can only apply the objects granted to the agent by other bindings.",
+			strings.Join(rbacModes, "|")),
+	)
+}
+
+// Validate checks the rbac mode.
+func (o *RBACOptions) Validate() error {
+	switch o.mode() {
+	case RBACModeAdmin, RBACModeMinimal:
+		return nil
+	default:
+		return fmt.Errorf("unsupported rbac mode %q, the supported modes are %s",
+			o.RBACMode, strings.Join(rbacModes, ", "))
+	}
+}
+
+// mode returns the rbac mode, the admin mode is the default.
+func (o *RBACOptions) mode() string {
+	if o.RBACMode == "" {
+		return RBACModeAdmin
+	}
+	return o.RBACMode
+}
+
+// checkMinimalRBAC returns a healthy status that warns the manifest works are limited in the minimal
+// rbac mode.
+func checkMinimalRBAC() ComponentStatus {
+	return ComponentStatus{
+		Name:    "rbac mode",
+		Healthy: true,
+		Reason: "minimal, the agent can only manage the applied manifest works and read the cluster claims, " +
+			"bind it to more roles to deploy other objects with manifest works",
+	}
+}
+
+// rbacFiles returns the files with the role files of the minimal mode, the cluster role is created
+// before its binding, the role and its binding are created with the service account, after the
+// namespace is created.
+func (o *RBACOptions) rbacFiles(files []string) []string {
+	if o.mode() != RBACModeMinimal {
+		return files
+	}
+
+	result := []string{}
+	for _, file := range files {
+		dir := path.Dir(file)
+		switch path.Base(file) {
+		case "clusterrolebinding.yaml":
+			result = append(result, path.Join(dir, "clusterrole.yaml"), file)
+		case "serviceaccount.yaml":
+			result = append(result, file, path.Join(dir, "role.yaml"), path.Join(dir, "rolebinding.yaml"))
+		default:
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
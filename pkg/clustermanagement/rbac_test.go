@@ -0,0 +1,222 @@
+package clustermanagement
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+func TestValidateRBAC(t *testing.T) {
+	for mode, valid := range map[string]bool{"": true, RBACModeAdmin: true, RBACModeMinimal: true, "none": false} {
+		err := (&RBACOptions{RBACMode: mode}).Validate()
+		if valid && err != nil {
+			t.Errorf("unexpected error with %q: %v", mode, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("expected error with %q", mode)
+		}
+	}
+}
+
+func TestRBACObjects(t *testing.T) {
+	kinds := func(objects []runtime.Object) []string {
+		result := []string{}
+		for _, obj := range objects {
+			result = append(result, obj.GetObjectKind().GroupVersionKind().Kind)
+		}
+		return result
+	}
+	roleRef := func(objects []runtime.Object) string {
+		for _, obj := range objects {
+			if binding, ok := obj.(*rbacv1.ClusterRoleBinding); ok {
+				return binding.RoleRef.Name
+			}
+		}
+		return ""
+	}
+
+	cases := []struct {
+		name                string
+		mode                string
+		expectedConnector   []string
+		expectedAgent       []string
+		expectedClusterRole string
+	}{
+		{
+			name:                "admin",
+			mode:                RBACModeAdmin,
+			expectedConnector:   []string{"ClusterRoleBinding", "ServiceAccount", "Secret", "Deployment"},
+			expectedAgent:       []string{"ClusterRoleBinding", "Namespace", "ServiceAccount", "Secret", "Deployment"},
+			expectedClusterRole: "cluster-admin",
+		},
+		{
+			name: "minimal",
+			mode: RBACModeMinimal,
+			expectedConnector: []string{
+				"ClusterRole", "ClusterRoleBinding", "ServiceAccount", "Role", "RoleBinding", "Secret", "Deployment",
+			},
+			expectedAgent: []string{
+				"ClusterRole", "ClusterRoleBinding", "Namespace", "ServiceAccount", "Role", "RoleBinding", "Secret",
+				"Deployment",
+			},
+			expectedClusterRole: "multicluster-controlplane",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rbac := RBACOptions{RBACMode: c.mode}
+			d := &controlPlaneDeployer{
+				config: &ControlPlaneConfig{Namespace: "xcm", Replicas: 1, RBACMode: rbac.mode()},
+				rbac:   rbac,
+			}
+			connector := d.controlPlaneObjects()
+			if !reflect.DeepEqual(kinds(connector), c.expectedConnector) {
+				t.Errorf("expected connector objects %v, but got %v", c.expectedConnector, kinds(connector))
+			}
+			if roleRef(connector) != c.expectedClusterRole {
+				t.Errorf("expected the connector is bound to %s, but got %s", c.expectedClusterRole, roleRef(connector))
+			}
+
+			agent := (&SpokeDeployer{rbac: rbac}).agentObjects()
+			if !reflect.DeepEqual(kinds(agent), c.expectedAgent) {
+				t.Errorf("expected agent objects %v, but got %v", c.expectedAgent, kinds(agent))
+			}
+			if c.mode == RBACModeMinimal && roleRef(agent) != "multicluster-controlplane-agent" {
+				t.Errorf("expected the agent is bound to its cluster role, but got %s", roleRef(agent))
+			}
+		})
+	}
+}
+
+// permission is a verb on a resource that a component requires, an empty namespace is a cluster wide
+// permission and an empty name is any object.
+type permission struct {
+	namespace, group, resource, verb, name string
+}
+
+func (p permission) String() string {
+	return fmt.Sprintf("%s %s.%s/%s in %q", p.verb, p.resource, p.group, p.name, p.namespace)
+}
+
+// rbacPermissions returns the permissions granted by the cluster roles and the roles of the objects,
+// a rule with resource names grants the permission on each name.
+func rbacPermissions(objects []runtime.Object) []permission {
+	result := []permission{}
+	add := func(namespace string, rules []rbacv1.PolicyRule) {
+		for _, rule := range rules {
+			names := rule.ResourceNames
+			if len(names) == 0 {
+				names = []string{""}
+			}
+			for _, group := range rule.APIGroups {
+				for _, resource := range rule.Resources {
+					for _, verb := range rule.Verbs {
+						for _, name := range names {
+							result = append(result, permission{namespace, group, resource, verb, name})
+						}
+					}
+				}
+			}
+		}
+	}
+	for _, obj := range objects {
+		switch role := obj.(type) {
+		case *rbacv1.ClusterRole:
+			add("", role.Rules)
+		case *rbacv1.Role:
+			add(role.Namespace, role.Rules)
+		}
+	}
+	return result
+}
+
+func TestMinimalRBACPermissions(t *testing.T) {
+	const appliedManifestWorkCRD = "appliedmanifestworks.work.open-cluster-management.io"
+
+	// agentPermissions are the permissions of the registration and work agents on their cluster, the
+	// control plane runs them in process to manage the hosting cluster.
+	agentPermissions := func(namespace string) []permission {
+		result := []permission{}
+		for _, verb := range []string{"get", "list", "watch"} {
+			result = append(result,
+				permission{"", "cluster.open-cluster-management.io", "clusterclaims", verb, ""},
+				permission{"", "", "nodes", verb, ""},
+			)
+		}
+		for _, resource := range []string{
+			"appliedmanifestworks", "appliedmanifestworks/status", "appliedmanifestworks/finalizers",
+		} {
+			for _, verb := range []string{"get", "list", "watch", "create", "update", "patch", "delete"} {
+				result = append(result, permission{"", "work.open-cluster-management.io", resource, verb, ""})
+			}
+		}
+		result = append(result,
+			permission{"", "apiextensions.k8s.io", "customresourcedefinitions", "create", ""},
+			permission{"", "apiextensions.k8s.io", "customresourcedefinitions", "get", appliedManifestWorkCRD},
+			permission{"", "apiextensions.k8s.io", "customresourcedefinitions", "update", appliedManifestWorkCRD},
+			permission{"", "authorization.k8s.io", "subjectaccessreviews", "create", ""},
+		)
+		for _, verb := range []string{"get", "list", "watch", "create", "update"} {
+			result = append(result,
+				permission{namespace, "", "secrets", verb, ""},
+				permission{namespace, "coordination.k8s.io", "leases", verb, ""},
+			)
+		}
+		for _, group := range []string{"", "events.k8s.io"} {
+			for _, verb := range []string{"create", "patch", "update"} {
+				result = append(result, permission{namespace, group, "events", verb, ""})
+			}
+		}
+		return result
+	}
+
+	rbac := RBACOptions{RBACMode: RBACModeMinimal}
+	d := &controlPlaneDeployer{
+		config: &ControlPlaneConfig{Namespace: "xcm", Replicas: 1, RBACMode: rbac.mode()},
+		rbac:   rbac,
+	}
+
+	cases := []struct {
+		name     string
+		objects  []runtime.Object
+		required []permission
+	}{
+		{
+			name:     "connector",
+			objects:  d.controlPlaneObjects(),
+			required: agentPermissions("xcm"),
+		},
+		{
+			name:     "agent",
+			objects:  (&SpokeDeployer{rbac: rbac}).agentObjects(),
+			required: agentPermissions(constants.DefaultControlPlaneAgentNamespace),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			granted := map[permission]bool{}
+			for _, p := range rbacPermissions(c.objects) {
+				granted[p] = true
+			}
+			required := map[permission]bool{}
+			for _, p := range c.required {
+				required[p] = true
+				if !granted[p] {
+					t.Errorf("the %s is not granted to %s", c.name, p)
+				}
+			}
+			for p := range granted {
+				if !required[p] {
+					t.Errorf("the %s is granted to %s, which it does not require", c.name, p)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package clustermanagement
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+
+	"github.com/skeeey/xcm-cli/pkg/resource"
+)
+
+const (
+	// DryRunNone applies the objects.
+	DryRunNone = "none"
+
+	// DryRunClient prints the objects that would be applied without sending them to the server.
+	DryRunClient = "client"
+
+	// DryRunServer submits the objects to the server in the dry-run mode, the objects are validated
+	// but not persisted.
+	DryRunServer = "server"
+)
+
+// Manifests are the ordered objects that are applied to connect or relay a cluster.
+type Manifests struct {
+	// Objects are applied to the cluster.
+	Objects []runtime.Object
+
+	// ControlPlaneObjects are applied to the control plane that the cluster is relayed to.
+	ControlPlaneObjects []runtime.Object
+}
+
+// ValidateDryRun checks if the dry-run strategy is valid.
+func ValidateDryRun(strategy string) error {
+	switch strategy {
+	case DryRunNone, DryRunClient, DryRunServer:
+		return nil
+	default:
+		return fmt.Errorf("invalid dry-run value %q, the valid values are %s", strategy,
+			strings.Join([]string{DryRunNone, DryRunClient, DryRunServer}, "|"))
+	}
+}
+
+// WriteYAML writes the manifests as a multi-document YAML in the order they are applied.
+func (m *Manifests) WriteYAML(w io.Writer) error {
+	if err := writeYAML(w, m.Objects); err != nil {
+		return err
+	}
+
+	if len(m.ControlPlaneObjects) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(w, "# The objects below are applied to the control plane.")
+	return writeYAML(w, m.ControlPlaneObjects)
+}
+
+func writeYAML(w io.Writer, objects []runtime.Object) error {
+	for _, obj := range objects {
+		required, err := resource.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(required.Object)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, "---")
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dryRun prints the objects that would be applied with the client strategy, or submits them to the
+// server in the dry-run mode with the server strategy. The control plane objects are submitted with
+// the control plane client.
+func dryRun(ctx context.Context, w io.Writer, strategy string, manifests *Manifests,
+	dynamicClient, controlPlaneDynamicClient dynamic.Interface) error {
+	errs := dryRunObjects(ctx, w, strategy, manifests.Objects, dynamicClient)
+	errs = append(errs, dryRunObjects(ctx, w, strategy, manifests.ControlPlaneObjects, controlPlaneDynamicClient)...)
+	return utilerrors.NewAggregate(errs)
+}
+
+func dryRunObjects(ctx context.Context, w io.Writer, strategy string,
+	objects []runtime.Object, dynamicClient dynamic.Interface) []error {
+	// the namespaces that are created by the objects, the namespaced objects cannot be validated by
+	// the server until their namespaces are created.
+	namespaces := map[string]bool{}
+
+	errs := []error{}
+	for _, obj := range objects {
+		required, err := resource.ToUnstructured(obj)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		name := fmt.Sprintf("%s/%s", strings.ToLower(required.GetKind()), required.GetName())
+		if required.GetKind() == "Namespace" {
+			namespaces[required.GetName()] = true
+		}
+
+		if strategy == DryRunClient {
+			fmt.Fprintf(w, "%s applied (client dry run)\n", name)
+			continue
+		}
+
+		err = resource.DryRunApply(ctx, dynamicClient, obj, false)
+		switch {
+		case errors.IsNotFound(err) && namespaces[required.GetNamespace()]:
+			fmt.Fprintf(w, "%s skipped (server dry run), the namespace %s is not created yet\n",
+				name, required.GetNamespace())
+		case err != nil:
+			fmt.Fprintf(w, "%s failed (server dry run): %v\n", name, err)
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+		default:
+			fmt.Fprintf(w, "%s applied (server dry run)\n", name)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,96 @@
+package clustermanagement
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	fakecluster "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/managedcluster"
+)
+
+func TestSpokeDeployerRender(t *testing.T) {
+	d := &SpokeDeployer{
+		kubeClient: fake.NewSimpleClientset(&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uid1"},
+		}),
+		spokeClusterClient: fakecluster.NewSimpleClientset(&clusterv1alpha1.ClusterClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: constants.ClusterIDClaimName},
+			Spec:       clusterv1alpha1.ClusterClaimSpec{Value: "c1"},
+		}),
+		hubClusterClient: fakecluster.NewSimpleClientset(),
+		hubConfig:        &rest.Config{Host: "https://hub:443"},
+	}
+
+	manifests, err := d.Render(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d.GetClusterID() != "c1" {
+		t.Errorf("expected the cluster id is reused, but got %q", d.GetClusterID())
+	}
+
+	out := &bytes.Buffer{}
+	if err := manifests.WriteYAML(out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rendered := out.String()
+	ordered := []string{
+		"kind: ClusterClaim",
+		"kind: ClusterRoleBinding",
+		"kind: Namespace",
+		"kind: Deployment",
+		"# The objects below are applied to the control plane.",
+		"kind: ManagedCluster",
+		"name: " + managedcluster.GetClusterName("c1"),
+		"namespace: " + constants.DefaultBootstrapNamespace,
+		"name: xcm:bootstrap:" + managedcluster.GetClusterName("c1"),
+	}
+	last := -1
+	for _, s := range ordered {
+		i := strings.Index(rendered, s)
+		if i <= last {
+			t.Fatalf("expected %q is rendered in order, but got\n%s", s, rendered)
+		}
+		last = i
+	}
+	if strings.Contains(rendered, "status:") || strings.Contains(rendered, "creationTimestamp") {
+		t.Errorf("expected the status and the creation timestamp are removed, but got\n%s", rendered)
+	}
+
+	// the objects are not applied
+	if _, err := d.hubClusterClient.ClusterV1().ManagedClusters().Get(
+		context.TODO(), managedcluster.GetClusterName("c1"), metav1.GetOptions{}); err == nil {
+		t.Errorf("expected the managed cluster is not created")
+	}
+
+	out.Reset()
+	if err := dryRun(context.TODO(), out, DryRunClient, manifests, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "managedcluster/cluster-c1 applied (client dry run)") {
+		t.Errorf("unexpected dry run output\n%s", out.String())
+	}
+}
+
+func TestValidateDryRun(t *testing.T) {
+	for _, strategy := range []string{DryRunNone, DryRunClient, DryRunServer} {
+		if err := ValidateDryRun(strategy); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if err := ValidateDryRun("all"); err == nil {
+		t.Errorf("expected error with an invalid strategy")
+	}
+}
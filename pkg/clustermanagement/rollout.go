@@ -0,0 +1,183 @@
+package clustermanagement
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/skeeey/xcm-cli/pkg/genericflags"
+)
+
+const (
+	// revisionAnnotation is the revision of a deployment and its replica sets.
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+
+	// restartedAtAnnotation is the annotation of the pod template that restarts the pods of a
+	// deployment, it's the same annotation as 'kubectl rollout restart'.
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+	// progressDeadlineExceeded is the reason of the progressing condition when a deployment fails to
+	// roll out in its progress deadline.
+	progressDeadlineExceeded = "ProgressDeadlineExceeded"
+)
+
+// rolloutImages updates the images of the containers of a deployment and waits until the deployment
+// is rolled out, the key of the images is the container name. The image pull secret is added to the
+// pods if it's not empty. If the rollout fails, the deployment is rolled back to its previous replica
+// set.
+func rolloutImages(ctx context.Context, w io.Writer, kubeClient kubernetes.Interface,
+	namespace, name string, images map[string]string, imagePullSecret string) error {
+	deploy, err := kubeClient.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	required := deploy.DeepCopy()
+	changed := false
+	for i, container := range required.Spec.Template.Spec.Containers {
+		image, ok := images[container.Name]
+		if !ok || image == container.Image {
+			continue
+		}
+
+		fmt.Fprintf(w, "deployment/%s container %s: %s -> %s\n", name, container.Name, container.Image, image)
+		required.Spec.Template.Spec.Containers[i].Image = image
+		changed = true
+	}
+
+	if imagePullSecret != "" && !hasImagePullSecret(required.Spec.Template.Spec.ImagePullSecrets, imagePullSecret) {
+		fmt.Fprintf(w, "deployment/%s image pull secret: %s\n", name, imagePullSecret)
+		required.Spec.Template.Spec.ImagePullSecrets = append(required.Spec.Template.Spec.ImagePullSecrets,
+			corev1.LocalObjectReference{Name: imagePullSecret})
+		changed = true
+	}
+
+	if !changed {
+		fmt.Fprintf(w, "deployment/%s is up to date\n", name)
+		return waitForRollout(ctx, kubeClient, namespace, name)
+	}
+
+	if _, err := kubeClient.AppsV1().Deployments(namespace).Update(ctx, required, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	rolloutErr := waitForRollout(ctx, kubeClient, namespace, name)
+	if rolloutErr == nil {
+		return nil
+	}
+
+	fmt.Fprintf(w, "deployment/%s failed to roll out, roll back to revision %s ...\n",
+		name, deploy.Annotations[revisionAnnotation])
+	if err := rollbackDeployment(ctx, kubeClient, deploy); err != nil {
+		return fmt.Errorf("the rollout of deployment %s/%s failed: %v, and the rollback failed: %v",
+			namespace, name, rolloutErr, err)
+	}
+
+	return fmt.Errorf("the rollout of deployment %s/%s failed and it is rolled back: %v", namespace, name, rolloutErr)
+}
+
+func hasImagePullSecret(secrets []corev1.LocalObjectReference, name string) bool {
+	for _, secret := range secrets {
+		if secret.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// restartDeployment restarts the pods of a deployment and waits until the deployment is rolled out,
+// e.g. after the certificates that are mounted by the pods are updated.
+func restartDeployment(ctx context.Context, w io.Writer, kubeClient kubernetes.Interface, namespace, name string) error {
+	deploy, err := kubeClient.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	required := deploy.DeepCopy()
+	if required.Spec.Template.Annotations == nil {
+		required.Spec.Template.Annotations = map[string]string{}
+	}
+	required.Spec.Template.Annotations[restartedAtAnnotation] = time.Now().Format(time.RFC3339)
+	if _, err := kubeClient.AppsV1().Deployments(namespace).Update(ctx, required, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "deployment/%s restarted\n", name)
+	return waitForRollout(ctx, kubeClient, namespace, name)
+}
+
+// waitForRollout waits until the latest generation of a deployment is observed, and all of its
+// replicas are updated and available. It fails once the progress deadline of the deployment is
+// exceeded.
+func waitForRollout(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) error {
+	return wait.PollImmediate(1*time.Second, genericflags.TimeOut(), func() (bool, error) {
+		deploy, err := kubeClient.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if deploy.Generation > deploy.Status.ObservedGeneration {
+			return false, nil
+		}
+
+		for _, condition := range deploy.Status.Conditions {
+			if condition.Type == appsv1.DeploymentProgressing && condition.Reason == progressDeadlineExceeded {
+				return false, fmt.Errorf("the deployment %s/%s exceeded its progress deadline: %s",
+					namespace, name, condition.Message)
+			}
+		}
+
+		replicas := int32(1)
+		if deploy.Spec.Replicas != nil {
+			replicas = *deploy.Spec.Replicas
+		}
+
+		return deploy.Status.UpdatedReplicas == replicas &&
+			deploy.Status.Replicas == replicas &&
+			deploy.Status.AvailableReplicas == replicas, nil
+	})
+}
+
+// rollbackDeployment restores the pod template of the previous replica set of a deployment, the
+// previous replica set is the one that has the revision of the deployment before the rollout. If it's
+// not found, the pod template of the deployment before the rollout is restored.
+func rollbackDeployment(ctx context.Context, kubeClient kubernetes.Interface, previous *appsv1.Deployment) error {
+	template := previous.Spec.Template.DeepCopy()
+
+	replicaSets, err := kubeClient.AppsV1().ReplicaSets(previous.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(previous.Spec.Selector),
+	})
+	if err != nil {
+		return err
+	}
+
+	revision := previous.Annotations[revisionAnnotation]
+	for _, rs := range replicaSets.Items {
+		if !metav1.IsControlledBy(&rs, previous) || revision == "" || rs.Annotations[revisionAnnotation] != revision {
+			continue
+		}
+
+		template = rs.Spec.Template.DeepCopy()
+		delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		break
+	}
+
+	deploy, err := kubeClient.AppsV1().Deployments(previous.Namespace).Get(ctx, previous.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	deploy.Spec.Template = *template
+	if _, err := kubeClient.AppsV1().Deployments(previous.Namespace).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	return waitForRollout(ctx, kubeClient, previous.Namespace, previous.Name)
+}
@@ -0,0 +1,134 @@
+package clustermanagement
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+const badImage = "quay.io/open-cluster-management/multicluster-controlplane:bad"
+
+func newRolledOutDeployment(image string) *appsv1.Deployment {
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "controlplane",
+			Namespace:   "xcm",
+			UID:         "uid1",
+			Generation:  1,
+			Annotations: map[string]string{revisionAnnotation: "1"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "controlplane"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "controlplane"}},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "controlplane", Image: image},
+					{Name: "sidecar", Image: "sidecar:v1"},
+				}},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			Replicas:           1,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+		},
+	}
+}
+
+func newReplicaSet(deploy *appsv1.Deployment, revision string) *appsv1.ReplicaSet {
+	template := deploy.Spec.Template.DeepCopy()
+	template.Labels[appsv1.DefaultDeploymentUniqueLabelKey] = "hash" + revision
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "controlplane-" + revision,
+			Namespace:       deploy.Namespace,
+			Labels:          deploy.Spec.Template.Labels,
+			Annotations:     map[string]string{revisionAnnotation: revision},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deploy, appsv1.SchemeGroupVersion.WithKind("Deployment"))},
+		},
+		Spec: appsv1.ReplicaSetSpec{Template: *template},
+	}
+}
+
+// newRolloutClient returns a client that simulates the deployment controller, the deployment with the
+// bad image exceeds its progress deadline.
+func newRolloutClient(objects ...runtime.Object) *fake.Clientset {
+	kubeClient := fake.NewSimpleClientset(objects...)
+	kubeClient.PrependReactor("update", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		deploy := action.(clienttesting.UpdateAction).GetObject().(*appsv1.Deployment)
+		deploy.Generation++
+		deploy.Status.ObservedGeneration = deploy.Generation
+		deploy.Status.Conditions = nil
+		deploy.Status.UpdatedReplicas = *deploy.Spec.Replicas
+		if deploy.Spec.Template.Spec.Containers[0].Image == badImage {
+			deploy.Status.UpdatedReplicas = 0
+			deploy.Status.Conditions = []appsv1.DeploymentCondition{{
+				Type:   appsv1.DeploymentProgressing,
+				Status: corev1.ConditionFalse,
+				Reason: progressDeadlineExceeded,
+			}}
+		}
+		err := kubeClient.Tracker().Update(appsv1.SchemeGroupVersion.WithResource("deployments"), deploy, deploy.Namespace)
+		return true, deploy, err
+	})
+	return kubeClient
+}
+
+func TestRolloutImages(t *testing.T) {
+	deploy := newRolledOutDeployment("quay.io/open-cluster-management/multicluster-controlplane:v0.1.0")
+	kubeClient := newRolloutClient(deploy)
+
+	if err := rolloutImages(context.TODO(), io.Discard, kubeClient, "xcm", "controlplane", map[string]string{
+		"controlplane": "quay.io/open-cluster-management/multicluster-controlplane:v0.2.0",
+	}, "mirror-pull-secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual, err := kubeClient.AppsV1().Deployments("xcm").Get(context.TODO(), "controlplane", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual.Spec.Template.Spec.Containers[0].Image != "quay.io/open-cluster-management/multicluster-controlplane:v0.2.0" ||
+		actual.Spec.Template.Spec.Containers[1].Image != "sidecar:v1" {
+		t.Errorf("unexpected containers %v", actual.Spec.Template.Spec.Containers)
+	}
+	if secrets := actual.Spec.Template.Spec.ImagePullSecrets; len(secrets) != 1 || secrets[0].Name != "mirror-pull-secret" {
+		t.Errorf("unexpected image pull secrets %v", secrets)
+	}
+}
+
+func TestRolloutImagesRollback(t *testing.T) {
+	deploy := newRolledOutDeployment("quay.io/open-cluster-management/multicluster-controlplane:v0.1.0")
+	previous := newReplicaSet(deploy, "1")
+	// the replica set of an older revision is not used
+	older := newReplicaSet(newRolledOutDeployment("quay.io/open-cluster-management/multicluster-controlplane:v0.0.1"), "0")
+	kubeClient := newRolloutClient(deploy, previous, older)
+
+	err := rolloutImages(context.TODO(), io.Discard, kubeClient, "xcm", "controlplane", map[string]string{
+		"controlplane": badImage,
+	}, "")
+	if err == nil {
+		t.Fatalf("expected error when the rollout fails")
+	}
+
+	actual, err := kubeClient.AppsV1().Deployments("xcm").Get(context.TODO(), "controlplane", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual.Spec.Template.Spec.Containers[0].Image != "quay.io/open-cluster-management/multicluster-controlplane:v0.1.0" {
+		t.Errorf("expected the deployment is rolled back, but got %v", actual.Spec.Template.Spec.Containers)
+	}
+	if _, ok := actual.Spec.Template.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+		t.Errorf("expected the pod template hash is removed, but got %v", actual.Spec.Template.Labels)
+	}
+}
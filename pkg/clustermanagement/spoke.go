@@ -3,24 +3,30 @@ package clustermanagement
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
 
 	"github.com/skeeey/xcm-cli/pkg/configs"
 	"github.com/skeeey/xcm-cli/pkg/constants"
 	"github.com/skeeey/xcm-cli/pkg/genericflags"
+	"github.com/skeeey/xcm-cli/pkg/helpers"
 	"github.com/skeeey/xcm-cli/pkg/managedcluster"
 	"github.com/skeeey/xcm-cli/pkg/resource"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -32,46 +38,107 @@ var spokeDeployFiles = []string{
 	"manifests/spoke/deployment.yaml",
 }
 
+const spokeImagePullSecretFile = "manifests/spoke/image-pull-secret.yaml"
+
 type BootstrapKubeConfigGetter func(kubeconfigPath string) []byte
 
 type SpokeDeployer struct {
 	kubeClient          kubernetes.Interface
+	dynamicClient       dynamic.Interface
+	hubKubeClient       kubernetes.Interface
 	hubClusterClient    clusterclient.Interface
+	hubDynamicClient    dynamic.Interface
+	hubConfig           *rest.Config
 	spokeClusterClient  clusterclient.Interface
 	bootstrapKubeconfig []byte
+	bootstrapTokenTTL   time.Duration
 	clusterID           string
 	clusterName         string
 	host                string
+	hubHost             string
+	forceReregister     bool
+	// rebootstrap is true if the agent keeps the hub kubeconfig of a previous registration, the agent
+	// bootstraps again after the cluster is imported.
+	rebootstrap         bool
+	prune               bool
+	images              ImageOptions
+	rbac                RBACOptions
+	agentImage          string
+	imagePullSecretData []byte
+}
+
+// SpokeDeployerOptions are the options to build a spoke deployer.
+type SpokeDeployerOptions struct {
+	KubeconfigPath string
+
+	// ControlPlane is the id of the control plane that the cluster is relayed to, if it is empty,
+	// the current control plane context is used.
+	ControlPlane string
+
+	// ForceReregister registers the cluster again when the cluster is registered by another cluster
+	// on the control plane, or the cluster was relayed to a different control plane.
+	ForceReregister bool
+
+	// BootstrapTokenTTL is the lifetime of the token that the agent bootstraps with, the default value
+	// is DefaultBootstrapTokenTTL.
+	BootstrapTokenTTL time.Duration
+
+	// Prune deletes the objects of the agent that were applied by a previous relay but are no longer
+	// rendered.
+	Prune bool
+
+	// ImageOptions override the agent image, the control plane image and the connector image are
+	// ignored.
+	ImageOptions
+
+	// RBACOptions decide the permissions of the agent.
+	RBACOptions
+}
+
+// Validate checks the image and the rbac options.
+func (o *SpokeDeployerOptions) Validate() error {
+	if err := o.ImageOptions.Validate(); err != nil {
+		return err
+	}
+
+	if o.BootstrapTokenTTL != 0 && o.BootstrapTokenTTL < minBootstrapTokenTTL {
+		return fmt.Errorf("the bootstrap token ttl %s is less than %s", o.BootstrapTokenTTL, minBootstrapTokenTTL)
+	}
+
+	return o.RBACOptions.Validate()
 }
 
-func BuildSpokeDeployer(kubeconfigPath string) (*SpokeDeployer, error) {
-	configDir, err := configs.ConfigDir()
+func BuildSpokeDeployer(opts *SpokeDeployerOptions) (*SpokeDeployer, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	controlPlaneKubeconfigData, err := configs.ControlPlaneKubeConfig(opts.ControlPlane)
 	if err != nil {
 		return nil, err
 	}
 
-	controlPlaneKubeConfigFileName := filepath.Join(configDir, constants.ControlPlaneKubeAdminFileName)
-	controlPlaneKubeconfig, err := clientcmd.LoadFromFile(controlPlaneKubeConfigFileName)
+	controlPlaneKubeconfigRest, err := clientcmd.RESTConfigFromKubeConfig(controlPlaneKubeconfigData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load control plane kube admin config, %v", err)
 	}
 
-	controlPlaneKubeconfigRest, err := clientcmd.BuildConfigFromFlags("", controlPlaneKubeConfigFileName)
+	hubKubeClient, err := kubernetes.NewForConfig(controlPlaneKubeconfigRest)
 	if err != nil {
 		return nil, err
 	}
 
-	controlPlaneKubeconfigData, err := clientcmd.Write(*controlPlaneKubeconfig)
+	hubClusterClient, err := clusterclient.NewForConfig(controlPlaneKubeconfigRest)
 	if err != nil {
 		return nil, err
 	}
 
-	hubClusterClient, err := clusterclient.NewForConfig(controlPlaneKubeconfigRest)
+	hubDynamicClient, err := dynamic.NewForConfig(controlPlaneKubeconfigRest)
 	if err != nil {
 		return nil, err
 	}
 
-	kubeconfig, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	kubeconfig, err := clientcmd.BuildConfigFromFlags("", opts.KubeconfigPath)
 	if err != nil {
 		return nil, err
 	}
@@ -81,127 +148,460 @@ func BuildSpokeDeployer(kubeconfigPath string) (*SpokeDeployer, error) {
 		return nil, err
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
 	spokeClusterClient, err := clusterclient.NewForConfig(kubeconfig)
 	if err != nil {
 		return nil, err
 	}
 
+	bootstrapTokenTTL := opts.BootstrapTokenTTL
+	if bootstrapTokenTTL == 0 {
+		bootstrapTokenTTL = DefaultBootstrapTokenTTL
+	}
+
 	return &SpokeDeployer{
-		kubeClient:          kubeClient,
-		spokeClusterClient:  spokeClusterClient,
-		hubClusterClient:    hubClusterClient,
-		bootstrapKubeconfig: controlPlaneKubeconfigData,
-		host:                kubeconfig.Host,
+		kubeClient:         kubeClient,
+		dynamicClient:      dynamicClient,
+		spokeClusterClient: spokeClusterClient,
+		hubKubeClient:      hubKubeClient,
+		hubClusterClient:   hubClusterClient,
+		hubDynamicClient:   hubDynamicClient,
+		hubConfig:          controlPlaneKubeconfigRest,
+		bootstrapTokenTTL:  bootstrapTokenTTL,
+		host:               kubeconfig.Host,
+		hubHost:            controlPlaneKubeconfigRest.Host,
+		forceReregister:    opts.ForceReregister,
+		prune:              opts.Prune,
+		images:             opts.ImageOptions,
+		rbac:               opts.RBACOptions,
+		agentImage:         opts.image(opts.AgentImage, constants.DefaultAgentImage),
 	}, nil
 }
 
 func (d *SpokeDeployer) Relay(ctx context.Context) error {
+	if err := d.loadImagePullSecret(ctx); err != nil {
+		return err
+	}
+
 	fmt.Fprintln(os.Stdout, "Connect current cluster to xCM [managedcluster] ...")
 	if err := d.ensureCluster(ctx); err != nil {
 		return fmt.Errorf("faild to create cluster in the control plane, %v", err)
 	}
 
+	fmt.Fprintln(os.Stdout, "Connect current cluster to xCM [bootstrap] ...")
+	if err := d.ensureBootstrapKubeconfig(ctx); err != nil {
+		return fmt.Errorf("failed to create the bootstrap credential of current cluster, %v", err)
+	}
+
 	fmt.Fprintln(os.Stdout, "Connect current cluster to xCM [agent] ...")
 	if err := d.importCluster(ctx); err != nil {
 		return fmt.Errorf("faild to import current cluster to the control plane, %v", err)
 	}
 
-	// TODO: below claims should be detected automatically
-	if _, err := managedcluster.CreateClusterClaim(ctx, d.spokeClusterClient, &clusterv1alpha1.ClusterClaim{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "product.open-cluster-management.io",
-		},
-		Spec: clusterv1alpha1.ClusterClaimSpec{
-			Value: "EKS",
-		},
-	}); err != nil {
-		return fmt.Errorf("failed to create cluster claim: %v", err)
+	if d.rebootstrap {
+		if err := d.resetAgent(ctx, os.Stdout); err != nil {
+			return fmt.Errorf("failed to restart the agent of current cluster, %v", err)
+		}
 	}
 
-	if _, err := managedcluster.CreateClusterClaim(ctx, d.spokeClusterClient, &clusterv1alpha1.ClusterClaim{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "platform.open-cluster-management.io",
-		},
-		Spec: clusterv1alpha1.ClusterClaimSpec{
-			Value: "AWS",
-		},
-	}); err != nil {
-		return fmt.Errorf("failed to create cluster claim: %v", err)
+	if err := recordInventory(ctx, os.Stdout, d.applier(), d.inventory(), d.prune, d.agentObjects()); err != nil {
+		return err
 	}
 
-	if _, err := managedcluster.CreateClusterClaim(ctx, d.spokeClusterClient, &clusterv1alpha1.ClusterClaim{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "region.open-cluster-management.io",
-		},
-		Spec: clusterv1alpha1.ClusterClaimSpec{
-			Value: "us-west-1",
-		},
-	}); err != nil {
-		return fmt.Errorf("failed to create cluster claim: %v", err)
+	if err := applyClusterClaims(ctx, d.kubeClient, d.spokeClusterClient, nil); err != nil {
+		return err
 	}
 
 	fmt.Fprintln(os.Stdout, "Connect current cluster to xCM ...")
-	if err := managedcluster.WaitManagedClusterConnected(ctx, d.hubClusterClient, d.clusterName); err != nil {
+	if err := d.waitForClusterConnected(ctx); err != nil {
 		return fmt.Errorf("failed to connect current cluster to xCM: %v", err)
 	}
 
 	return nil
 }
 
+// Upgrade updates the image of the agent and waits until it is rolled out, the agent is rolled back if
+// the rollout fails.
+func (d *SpokeDeployer) Upgrade(ctx context.Context, w io.Writer) error {
+	// the image pull secret is copied into the namespace again, so that the new image can be pulled
+	// with the new credentials
+	if d.images.ImagePullSecret != "" {
+		if err := d.loadImagePullSecret(ctx); err != nil {
+			return err
+		}
+		if err := d.applier().Apply(ctx, d.inventory().Label(
+			mustCreateObjects([]string{spokeImagePullSecretFile}, d.agentConfig())...)...); err != nil {
+			return err
+		}
+	}
+
+	return rolloutImages(ctx, w, d.kubeClient, constants.DefaultControlPlaneAgentNamespace,
+		constants.ControlPlaneAgentName, map[string]string{"agent": d.agentImage}, d.images.imagePullSecretName())
+}
+
 func (d *SpokeDeployer) GetClusterID() string {
 	return d.clusterID
 }
 
-// create a cluster on the hub
+// Relayed checks if the agent is deployed on the cluster.
+func (d *SpokeDeployer) Relayed(ctx context.Context) (bool, error) {
+	_, err := d.kubeClient.CoreV1().Namespaces().Get(ctx, constants.DefaultControlPlaneAgentNamespace, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Disconnect removes the cluster from the control plane, and removes the agent and the cluster
+// claims from the cluster.
+func (d *SpokeDeployer) Disconnect(ctx context.Context) error {
+	clusterID, err := managedcluster.GetClusterClaim(ctx, d.spokeClusterClient, constants.ClusterIDClaimName)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster claim: %v", err)
+	}
+
+	if clusterID != "" {
+		d.clusterID = clusterID
+		d.clusterName = managedcluster.GetClusterName(clusterID)
+
+		fmt.Fprintln(os.Stdout, "Disconnect current cluster from xCM [managedcluster] ...")
+		if err := managedcluster.DeleteManagedCluster(ctx, d.hubClusterClient, d.clusterName); err != nil {
+			return fmt.Errorf("failed to delete cluster from the control plane, %v", err)
+		}
+
+		if err := d.removeBootstrapObjects(ctx); err != nil {
+			return fmt.Errorf("failed to remove the bootstrap credential from the control plane, %v", err)
+		}
+	}
+
+	fmt.Fprintln(os.Stdout, "Disconnect current cluster from xCM [agent] ...")
+	objects := d.agentObjects()
+	// the objects that were applied by a previous version but are no longer rendered are removed too
+	if _, err := d.inventory().Prune(ctx, os.Stdout, d.applier(), objects...); err != nil {
+		return fmt.Errorf("failed to remove agent: %v", err)
+	}
+	if err := resource.DeleteResources(ctx, d.kubeClient, nil, nil, reverse(objects)...); err != nil {
+		return fmt.Errorf("failed to remove agent: %v", err)
+	}
+
+	if err := waitForNamespaceDeleted(ctx, d.kubeClient, constants.DefaultControlPlaneAgentNamespace); err != nil {
+		return fmt.Errorf("failed to remove namespace %s: %v", constants.DefaultControlPlaneAgentNamespace, err)
+	}
+
+	if err := managedcluster.DeleteClusterClaims(ctx, d.spokeClusterClient,
+		append([]string{constants.ClusterIDClaimName}, clusterInfoClaimNames...)...); err != nil {
+		return fmt.Errorf("failed to delete cluster claims: %v", err)
+	}
+
+	return nil
+}
+
+// Render returns the cluster claims and the objects of the agent that are applied to the cluster, and
+// the managed cluster and the bootstrap objects that are created on the control plane. The identity of
+// the cluster is reused if the cluster was relayed. The bootstrap token is minted only when the cluster
+// is relayed, a placeholder is used instead.
+func (d *SpokeDeployer) Render(ctx context.Context) (*Manifests, error) {
+	clusterUID, err := d.getClusterUID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterID, err := managedcluster.GetClusterClaim(ctx, d.spokeClusterClient, constants.ClusterIDClaimName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster claim: %v", err)
+	}
+	if clusterID == "" {
+		clusterID = managedcluster.GetClusterID()
+	}
+	d.clusterID = clusterID
+	d.clusterName = managedcluster.GetClusterName(clusterID)
+
+	if err := d.loadImagePullSecret(ctx); err != nil {
+		return nil, err
+	}
+
+	claims, err := buildClusterClaims(ctx, d.kubeClient, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "The bootstrap token is minted when the cluster is relayed, "+
+		"replace %s with a token of the service account %s/%s in the rendered manifests\n",
+		bootstrapTokenPlaceholder, constants.DefaultBootstrapNamespace, bootstrapServiceAccountName(d.clusterName))
+	d.bootstrapKubeconfig, err = buildBootstrapKubeconfig(d.hubConfig, bootstrapTokenPlaceholder)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []runtime.Object{newClusterClaim(constants.ClusterIDClaimName, clusterID)}
+	objects = append(objects, d.agentObjects()...)
+	for _, claim := range claims {
+		objects = append(objects, claim)
+	}
+
+	return &Manifests{
+		Objects: objects,
+		ControlPlaneObjects: append([]runtime.Object{
+			managedcluster.NewManagedCluster(d.clusterName, map[string]string{
+				constants.ClusterUIDAnnotation: clusterUID,
+			}),
+		}, d.bootstrapObjects()...),
+	}, nil
+}
+
+// DryRun prints or validates the objects that are applied to relay the cluster with the given dry-run
+// strategy, the managed cluster is validated by the control plane.
+func (d *SpokeDeployer) DryRun(ctx context.Context, w io.Writer, strategy string) error {
+	manifests, err := d.Render(ctx)
+	if err != nil {
+		return err
+	}
+
+	return dryRun(ctx, w, strategy, manifests, d.dynamicClient, d.hubDynamicClient)
+}
+
+// Preflight checks the permissions to deploy the agent, the namespace of the agent, the cluster claim
+// CRD and the images of the agent, all the problems are reported at once.
+func (d *SpokeDeployer) Preflight(ctx context.Context) ([]ComponentStatus, error) {
+	objects := d.agentObjects()
+
+	statuses := []ComponentStatus{
+		checkPermissions(ctx, d.kubeClient, objects),
+		checkNamespaces(ctx, d.kubeClient, constants.DefaultControlPlaneAgentNamespace),
+		checkClusterClaimCRD(d.kubeClient),
+		checkImages(ctx, d.dynamicClient, objects),
+	}
+
+	if d.rbac.mode() == RBACModeMinimal {
+		statuses = append(statuses, checkMinimalRBAC())
+	}
+
+	if d.images.ImagePullSecret != "" {
+		statuses = append(statuses, checkImagePullSecret(ctx, d.kubeClient, &d.images))
+	}
+
+	return statuses, nil
+}
+
+// Status returns the status of the agent on the cluster and the managed cluster on the control plane.
+func (d *SpokeDeployer) Status(ctx context.Context) ([]ComponentStatus, error) {
+	statuses := []ComponentStatus{}
+
+	agent := ComponentStatus{Name: "agent"}
+	deploy, err := d.kubeClient.AppsV1().Deployments(constants.DefaultControlPlaneAgentNamespace).Get(
+		ctx, constants.ControlPlaneAgentName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		agent.Reason = fmt.Sprintf("the deployment %s/%s is not found",
+			constants.DefaultControlPlaneAgentNamespace, constants.ControlPlaneAgentName)
+	case err != nil:
+		return nil, err
+	case helpers.NumOfUnavailablePod(deploy) > 0:
+		agent.Reason = fmt.Sprintf("%d pod(s) of the deployment %s/%s are unavailable",
+			helpers.NumOfUnavailablePod(deploy), constants.DefaultControlPlaneAgentNamespace, constants.ControlPlaneAgentName)
+	default:
+		agent.Healthy = true
+	}
+	statuses = append(statuses, agent)
+
+	clusterID, err := managedcluster.GetClusterClaim(ctx, d.spokeClusterClient, constants.ClusterIDClaimName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster claim: %v", err)
+	}
+	d.clusterID = clusterID
+
+	cluster := ComponentStatus{Name: "managed cluster"}
+	if clusterID == "" {
+		cluster.Reason = fmt.Sprintf("the cluster claim %s is not found", constants.ClusterIDClaimName)
+		return append(statuses, cluster), nil
+	}
+
+	d.clusterName = managedcluster.GetClusterName(clusterID)
+	mc, err := d.hubClusterClient.ClusterV1().ManagedClusters().Get(ctx, d.clusterName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		cluster.Reason = fmt.Sprintf("the managed cluster %s is not found on the control plane", d.clusterName)
+	case err != nil:
+		// the control plane is unreachable is a failure of the managed cluster
+		cluster.Reason = fmt.Sprintf("failed to get the managed cluster %s from the control plane: %v", d.clusterName, err)
+	default:
+		reasons := []string{}
+		for _, conditionType := range []string{
+			clusterv1.ManagedClusterConditionAvailable,
+			managedcluster.ManagedClusterConditionConnected,
+		} {
+			condition := meta.FindStatusCondition(mc.Status.Conditions, conditionType)
+			switch {
+			case condition == nil:
+				reasons = append(reasons, fmt.Sprintf("the condition %s is unknown", conditionType))
+			case condition.Status != metav1.ConditionTrue:
+				reasons = append(reasons, fmt.Sprintf("the condition %s is %s: %s",
+					conditionType, condition.Status, condition.Message))
+			}
+		}
+
+		cluster.Healthy = len(reasons) == 0
+		cluster.Reason = strings.Join(reasons, "; ")
+	}
+	statuses = append(statuses, cluster)
+
+	return statuses, nil
+}
+
+// ensureCluster reconciles the identity of current cluster and creates the cluster on the hub.
+// If current cluster was relayed, its identity is reused.
 func (d *SpokeDeployer) ensureCluster(ctx context.Context) error {
-	clusterID, err := managedcluster.CreateClusterClaim(ctx, d.spokeClusterClient, &clusterv1alpha1.ClusterClaim{
+	clusterUID, err := d.getClusterUID(ctx)
+	if err != nil {
+		return err
+	}
+
+	existingClusterID, err := managedcluster.GetClusterClaim(ctx, d.spokeClusterClient, constants.ClusterIDClaimName)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster claim: %v", err)
+	}
+
+	clusterID := existingClusterID
+	if clusterID == "" {
+		clusterID = managedcluster.GetClusterID()
+	}
+
+	if existingClusterID != "" {
+		if err := d.checkControlPlane(ctx); err != nil {
+			return err
+		}
+
+		bound, err := d.boundToOtherCluster(ctx, managedcluster.GetClusterName(existingClusterID), clusterUID)
+		if err != nil {
+			return err
+		}
+
+		if bound {
+			if !d.forceReregister {
+				return fmt.Errorf("the cluster %s is registered by another cluster on the control plane, "+
+					"use --force-reregister to register current cluster with a new identity", existingClusterID)
+			}
+
+			fmt.Fprintf(os.Stdout, "The cluster %s is registered by another cluster, register current cluster with a new identity\n",
+				existingClusterID)
+			clusterID = managedcluster.GetClusterID()
+			d.rebootstrap = true
+		}
+	}
+
+	if err := managedcluster.ApplyClusterClaim(ctx, d.spokeClusterClient, &clusterv1alpha1.ClusterClaim{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "xcmid.open-cluster-management.io",
+			Name: constants.ClusterIDClaimName,
 		},
 		Spec: clusterv1alpha1.ClusterClaimSpec{
-			Value: managedcluster.GetClusterID(),
+			Value: clusterID,
 		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create cluster claim: %v", err)
+	}); err != nil {
+		return fmt.Errorf("failed to apply cluster claim: %v", err)
 	}
 	clusterName := managedcluster.GetClusterName(clusterID)
 
-	// TODO check if cluster exists (a same cluster connected then relay)
-	if err := managedcluster.CreateManagedCluster(ctx, d.hubClusterClient, clusterName); err != nil {
+	if err := managedcluster.CreateManagedCluster(ctx, d.hubClusterClient, clusterName, map[string]string{
+		constants.ClusterUIDAnnotation: clusterUID,
+	}); err != nil {
 		return err
 	}
 
-	// TODO create cluster claim
-
 	d.clusterID = clusterID
 	d.clusterName = clusterName
 
 	return nil
 }
 
-func (d *SpokeDeployer) importCluster(ctx context.Context) error {
-	config := struct {
-		BootstrapKubeconfig []byte
-		ClusterName         string
-		Namespace           string
-	}{
-		BootstrapKubeconfig: d.bootstrapKubeconfig,
-		ClusterName:         d.clusterName,
-		Namespace:           constants.DefaultControlPlaneAgentNamespace,
+// checkControlPlane checks if current cluster was relayed to a different control plane by comparing
+// the server of the existing bootstrap kubeconfig with the server of the control plane.
+func (d *SpokeDeployer) checkControlPlane(ctx context.Context) error {
+	secret, err := d.kubeClient.CoreV1().Secrets(constants.DefaultControlPlaneAgentNamespace).Get(
+		ctx, constants.BootstrapKubeconfigSecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
-	objects := []runtime.Object{}
-	for _, file := range spokeDeployFiles {
-		template, err := manifestFiles.ReadFile(file)
-		if err != nil {
-			// this should not happen, if happened, panic here
-			panic(err)
-		}
+	server, err := kubeconfigServer(secret.Data["kubeconfig"])
+	if err != nil {
+		return fmt.Errorf("failed to load the bootstrap kubeconfig from the secret %s/%s: %v",
+			constants.DefaultControlPlaneAgentNamespace, constants.BootstrapKubeconfigSecretName, err)
+	}
 
-		objects = append(objects, resource.MustCreateObjectFromTemplate(file, template, config))
+	if server == "" || server == d.hubHost {
+		return nil
 	}
 
+	if !d.forceReregister {
+		return fmt.Errorf("the cluster was relayed to the control plane %s, "+
+			"use --force-reregister to relay it to the control plane %s", server, d.hubHost)
+	}
+
+	fmt.Fprintf(os.Stdout, "The cluster was relayed to the control plane %s, relay it to the control plane %s\n",
+		server, d.hubHost)
+
+	// the agent keeps the hub kubeconfig of the previous control plane, it bootstraps again once the
+	// new bootstrap kubeconfig is applied.
+	d.rebootstrap = true
+	return nil
+}
+
+// resetAgent deletes the hub kubeconfig of the previous registration and restarts the agent, so that
+// the agent bootstraps with the new bootstrap kubeconfig.
+func (d *SpokeDeployer) resetAgent(ctx context.Context, w io.Writer) error {
+	err := d.kubeClient.CoreV1().Secrets(constants.DefaultControlPlaneAgentNamespace).Delete(
+		ctx, constants.HubKubeconfigSecretName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return restartDeployment(ctx, w, d.kubeClient, constants.DefaultControlPlaneAgentNamespace,
+		constants.ControlPlaneAgentName)
+}
+
+// boundToOtherCluster checks if the managed cluster on the hub is registered by another cluster.
+func (d *SpokeDeployer) boundToOtherCluster(ctx context.Context, clusterName, clusterUID string) (bool, error) {
+	cluster, err := d.hubClusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	uid, ok := cluster.Annotations[constants.ClusterUIDAnnotation]
+	if !ok {
+		// the cluster was created by an old version, adopt it
+		return false, nil
+	}
+
+	return uid != clusterUID, nil
+}
+
+// getClusterUID returns the uid of the kube-system namespace, which identifies current cluster.
+func (d *SpokeDeployer) getClusterUID(ctx context.Context) (string, error) {
+	ns, err := d.kubeClient.CoreV1().Namespaces().Get(ctx, "kube-system", metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the kube-system namespace: %v", err)
+	}
+
+	return string(ns.UID), nil
+}
+
+func (d *SpokeDeployer) importCluster(ctx context.Context) error {
+	objects := d.agentObjects()
+
 	return wait.PollImmediate(1*time.Second, genericflags.TimeOut(), func() (bool, error) {
 		err := resource.ApplyResources(
 			ctx,
@@ -220,3 +620,79 @@ func (d *SpokeDeployer) importCluster(ctx context.Context) error {
 		return true, nil
 	})
 }
+
+// agentObjects returns the objects of the agent, the image pull secret is created before the
+// deployment, the roles are created in the minimal rbac mode.
+func (d *SpokeDeployer) agentObjects() []runtime.Object {
+	files := []string{}
+	for _, file := range d.rbac.rbacFiles(spokeDeployFiles) {
+		if file == "manifests/spoke/deployment.yaml" && d.images.imagePullSecretName() != "" {
+			files = append(files, spokeImagePullSecretFile)
+		}
+		files = append(files, file)
+	}
+
+	return d.inventory().Label(mustCreateObjects(files, d.agentConfig())...)
+}
+
+// inventory returns the inventory of the agent, it's in the namespace of the agent.
+func (d *SpokeDeployer) inventory() *resource.Inventory {
+	return &resource.Inventory{Install: agentInstall, Namespace: constants.DefaultControlPlaneAgentNamespace}
+}
+
+// applier returns the applier of the cluster, it's used to prune the objects of the agent.
+func (d *SpokeDeployer) applier() *resource.Applier {
+	return &resource.Applier{KubeClient: d.kubeClient, DynamicClient: d.dynamicClient}
+}
+
+func (d *SpokeDeployer) agentConfig() interface{} {
+	return struct {
+		BootstrapKubeconfig []byte
+		ClusterName         string
+		Namespace           string
+		AgentImage          string
+		ImagePullSecret     string
+		ImagePullSecretData []byte
+		RBACMode            string
+	}{
+		BootstrapKubeconfig: d.bootstrapKubeconfig,
+		ClusterName:         d.clusterName,
+		Namespace:           constants.DefaultControlPlaneAgentNamespace,
+		AgentImage:          d.agentImage,
+		ImagePullSecret:     d.images.imagePullSecretName(),
+		ImagePullSecretData: d.imagePullSecretData,
+		RBACMode:            d.rbac.mode(),
+	}
+}
+
+// loadImagePullSecret loads the docker config of the image pull secret that is copied into the
+// namespace of the agent.
+func (d *SpokeDeployer) loadImagePullSecret(ctx context.Context) error {
+	data, err := d.images.loadImagePullSecret(ctx, d.kubeClient)
+	if err != nil {
+		return err
+	}
+
+	d.imagePullSecretData = data
+	return nil
+}
+
+// kubeconfigServer returns the server of the current context of the kubeconfig.
+func kubeconfigServer(data []byte) (string, error) {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return "", err
+	}
+
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return "", nil
+	}
+
+	cluster, ok := config.Clusters[context.Cluster]
+	if !ok {
+		return "", nil
+	}
+
+	return cluster.Server, nil
+}
@@ -0,0 +1,177 @@
+package clustermanagement
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	fakecluster "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/managedcluster"
+)
+
+func newAgent(available int32) *appsv1.Deployment {
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.ControlPlaneAgentName,
+			Namespace: constants.DefaultControlPlaneAgentNamespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas:          1,
+			AvailableReplicas: available,
+		},
+	}
+}
+
+func newManagedCluster(name string, conditions ...metav1.Condition) *clusterv1.ManagedCluster {
+	return &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     clusterv1.ManagedClusterStatus{Conditions: conditions},
+	}
+}
+
+func TestSpokeDeployerStatus(t *testing.T) {
+	clusterName := managedcluster.GetClusterName("c1")
+	claim := &clusterv1alpha1.ClusterClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: constants.ClusterIDClaimName},
+		Spec:       clusterv1alpha1.ClusterClaimSpec{Value: "c1"},
+	}
+
+	cases := []struct {
+		name          string
+		kubeObjects   []runtime.Object
+		spokeObjects  []runtime.Object
+		hubObjects    []runtime.Object
+		expectHealthy []bool
+		expectReason  string
+	}{
+		{
+			name:          "not relayed",
+			expectHealthy: []bool{false, false},
+			expectReason:  "is not found",
+		},
+		{
+			name:         "healthy",
+			kubeObjects:  []runtime.Object{newAgent(1)},
+			spokeObjects: []runtime.Object{claim},
+			hubObjects: []runtime.Object{newManagedCluster(clusterName,
+				metav1.Condition{Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionTrue},
+				metav1.Condition{Type: managedcluster.ManagedClusterConditionConnected, Status: metav1.ConditionTrue},
+			)},
+			expectHealthy: []bool{true, true},
+		},
+		{
+			name:         "unavailable",
+			kubeObjects:  []runtime.Object{newAgent(0)},
+			spokeObjects: []runtime.Object{claim},
+			hubObjects: []runtime.Object{newManagedCluster(clusterName,
+				metav1.Condition{Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionUnknown,
+					Message: "lease is not updated"},
+			)},
+			expectHealthy: []bool{false, false},
+			expectReason:  "lease is not updated",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := &SpokeDeployer{
+				kubeClient:         fake.NewSimpleClientset(c.kubeObjects...),
+				spokeClusterClient: fakecluster.NewSimpleClientset(c.spokeObjects...),
+				hubClusterClient:   fakecluster.NewSimpleClientset(c.hubObjects...),
+			}
+
+			statuses, err := d.Status(context.TODO())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(statuses) != len(c.expectHealthy) {
+				t.Fatalf("expected %d statuses, but got %v", len(c.expectHealthy), statuses)
+			}
+			for i, status := range statuses {
+				if status.Healthy != c.expectHealthy[i] {
+					t.Errorf("unexpected status %v", status)
+				}
+			}
+			if c.expectReason != "" && !strings.Contains(statuses[len(statuses)-1].Reason, c.expectReason) {
+				t.Errorf("expected reason %q, but got %v", c.expectReason, statuses)
+			}
+		})
+	}
+}
+
+func TestRebootstrapAgent(t *testing.T) {
+	bootstrapKubeconfig, err := buildBootstrapKubeconfig(&rest.Config{Host: "https://old:443"}, "token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	agent := newAgent(1)
+	agent.Status.UpdatedReplicas = 1
+	kubeClient := fake.NewSimpleClientset(
+		agent,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: constants.DefaultControlPlaneAgentNamespace,
+				Name:      constants.BootstrapKubeconfigSecretName,
+			},
+			Data: map[string][]byte{"kubeconfig": bootstrapKubeconfig},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: constants.DefaultControlPlaneAgentNamespace,
+				Name:      constants.HubKubeconfigSecretName,
+			},
+		},
+	)
+
+	d := &SpokeDeployer{kubeClient: kubeClient, hubHost: "https://new:443"}
+	if err := d.checkControlPlane(context.TODO()); err == nil {
+		t.Errorf("expected error when the cluster was relayed to another control plane")
+	}
+
+	d.forceReregister = true
+	if err := d.checkControlPlane(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !d.rebootstrap {
+		t.Errorf("expected the agent bootstraps again")
+	}
+	// the agent is not disrupted until the cluster is imported
+	for _, action := range kubeClient.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("unexpected action %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+
+	if err := d.resetAgent(context.TODO(), &bytes.Buffer{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := kubeClient.CoreV1().Secrets(constants.DefaultControlPlaneAgentNamespace).Get(
+		context.TODO(), constants.HubKubeconfigSecretName, metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the hub kubeconfig secret is deleted, but got %v", err)
+	}
+	deploy, err := kubeClient.AppsV1().Deployments(constants.DefaultControlPlaneAgentNamespace).Get(
+		context.TODO(), constants.ControlPlaneAgentName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deploy.Spec.Template.Annotations[restartedAtAnnotation] == "" {
+		t.Errorf("expected the agent is restarted")
+	}
+}
@@ -0,0 +1,267 @@
+package clustermanagement
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	dataVolumeClaimFile = "manifests/connector/pvc.yaml"
+
+	// standbyConnectorFile is the deployment of the connector replicas besides the connector in the
+	// control plane pod.
+	standbyConnectorFile = "manifests/connector/connector-deployment.yaml"
+
+	// dataVolumeClaimName is the name of the persistent volume claim of the control plane data
+	// directory.
+	dataVolumeClaimName = "multicluster-controlplane-data"
+
+	// defaultStorageClassAnnotation marks the default storage class of a cluster.
+	defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+)
+
+// StorageOptions are the options of the control plane data and its availability.
+type StorageOptions struct {
+	// StorageSize is the size of the persistent volume claim of the control plane data directory,
+	// the data directory is in memory if it's empty.
+	StorageSize string
+
+	// StorageClass is the storage class of the persistent volume claim, the default storage class of
+	// the cluster is used if it's empty.
+	StorageClass string
+
+	// EtcdServers are the endpoints of an external etcd, the control plane runs an embedded etcd if
+	// it's empty.
+	EtcdServers []string
+
+	// EtcdCAFile, EtcdCertFile and EtcdKeyFile are the local files of the CA bundle and the client
+	// certificate to connect to the external etcd.
+	EtcdCAFile   string
+	EtcdCertFile string
+	EtcdKeyFile  string
+
+	// Replicas is the number of the connector replicas, the connector runs with leader election if
+	// it's more than one. The control plane server always runs with one replica, since its replicas
+	// cannot share the data directory, the other connector replicas read the data directory of the
+	// control plane from the persistent volume, so that it must support ReadWriteMany.
+	Replicas int32
+}
+
+// AddStorageFlags adds the flags of the control plane storage and the connector replicas to the given
+// set of command line flags.
+func AddStorageFlags(flags *pflag.FlagSet, opts *StorageOptions) {
+	flags.StringVar(
+		&opts.StorageSize,
+		"storage-size",
+		"",
+		"The size of a persistent volume claim for the data directory of the control plane, e.g. '10Gi'. "+
+			"The data directory is in memory by default and it is lost once the control plane restarts.",
+	)
+
+	flags.StringVar(
+		&opts.StorageClass,
+		"storage-class",
+		"",
+		"The storage class of the persistent volume claim, the default storage class of the cluster is used by default.",
+	)
+
+	flags.StringSliceVar(
+		&opts.EtcdServers,
+		"etcd-servers",
+		nil,
+		"The endpoints of an external etcd, e.g. 'https://etcd-0.example.com:2379'. "+
+			"The control plane runs an embedded etcd by default.",
+	)
+
+	flags.StringVar(
+		&opts.EtcdCAFile,
+		"etcd-ca-file",
+		"",
+		"The CA bundle file to verify the external etcd.",
+	)
+
+	flags.StringVar(
+		&opts.EtcdCertFile,
+		"etcd-cert-file",
+		"",
+		"The client certificate file to connect to the external etcd.",
+	)
+
+	flags.StringVar(
+		&opts.EtcdKeyFile,
+		"etcd-key-file",
+		"",
+		"The client key file to connect to the external etcd.",
+	)
+
+	flags.Int32Var(
+		&opts.Replicas,
+		"replicas",
+		1,
+		"The number of the xCM connector replicas, the connector runs with leader election if it's more than one. "+
+			"The control plane server always runs with one replica, since the replicas cannot share its data directory. "+
+			"More than one replica requires '--storage-size' with a storage class that supports ReadWriteMany.",
+	)
+}
+
+// Validate checks the storage size, the etcd endpoints and the replicas.
+func (o *StorageOptions) Validate() error {
+	if o.StorageSize != "" {
+		if _, err := apiresource.ParseQuantity(o.StorageSize); err != nil {
+			return fmt.Errorf("invalid storage size %q: %v", o.StorageSize, err)
+		}
+	}
+
+	if o.StorageClass != "" && o.StorageSize == "" {
+		return fmt.Errorf("the storage class is specified without the storage size")
+	}
+
+	for _, server := range o.EtcdServers {
+		u, err := url.Parse(server)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid etcd server %q, the format is <scheme>://<host>:<port>", server)
+		}
+	}
+
+	if len(o.EtcdServers) == 0 && (o.EtcdCAFile != "" || o.EtcdCertFile != "" || o.EtcdKeyFile != "") {
+		return fmt.Errorf("the etcd certificates are specified without the etcd servers")
+	}
+
+	if (o.EtcdCertFile == "") != (o.EtcdKeyFile == "") {
+		return fmt.Errorf("the etcd client certificate and key must be specified together")
+	}
+
+	if o.Replicas < 0 {
+		return fmt.Errorf("invalid replicas %d", o.Replicas)
+	}
+
+	if o.replicas() > 1 && o.StorageSize == "" {
+		return fmt.Errorf("more than one replica requires the storage size")
+	}
+
+	return nil
+}
+
+// replicas returns the number of the connector replicas, one replica is the default.
+func (o *StorageOptions) replicas() int32 {
+	if o.Replicas == 0 {
+		return 1
+	}
+	return o.Replicas
+}
+
+// standbyConnectorReplicas returns the number of the connector replicas besides the connector in the
+// control plane pod.
+func (o *StorageOptions) standbyConnectorReplicas() int32 {
+	return o.replicas() - 1
+}
+
+// accessMode returns the access mode of the persistent volume claim, the standby connectors read the
+// data directory from other pods.
+func (o *StorageOptions) accessMode() corev1.PersistentVolumeAccessMode {
+	if o.replicas() > 1 {
+		return corev1.ReadWriteMany
+	}
+	return corev1.ReadWriteOnce
+}
+
+// loadEtcdCerts reads the etcd certificate files into the control plane config, they are saved to
+// the control plane config secret.
+func (o *StorageOptions) loadEtcdCerts(config *ControlPlaneConfig) error {
+	for _, f := range []struct {
+		file string
+		data *[]byte
+	}{
+		{file: o.EtcdCAFile, data: &config.EtcdCA},
+		{file: o.EtcdCertFile, data: &config.EtcdCert},
+		{file: o.EtcdKeyFile, data: &config.EtcdKey},
+	} {
+		if f.file == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(f.file)
+		if err != nil {
+			return fmt.Errorf("failed to read etcd certificate: %v", err)
+		}
+		*f.data = data
+	}
+
+	return nil
+}
+
+// checkStorageClass checks if the storage class of the persistent volume claim exists, or the cluster
+// has a default storage class if no storage class is specified.
+func checkStorageClass(ctx context.Context, kubeClient kubernetes.Interface, storageClass string) ComponentStatus {
+	status := ComponentStatus{Name: "storage class"}
+
+	if storageClass != "" {
+		_, err := kubeClient.StorageV1().StorageClasses().Get(ctx, storageClass, metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+			status.Reason = fmt.Sprintf("the storage class %s is not found", storageClass)
+		case err != nil:
+			status.Reason = fmt.Sprintf("failed to get the storage class %s: %v", storageClass, err)
+		default:
+			status.Healthy = true
+			status.Reason = storageClass
+		}
+		return status
+	}
+
+	storageClasses, err := kubeClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		status.Reason = fmt.Sprintf("failed to list the storage classes: %v", err)
+		return status
+	}
+
+	if name := defaultStorageClass(storageClasses.Items); name != "" {
+		status.Healthy = true
+		status.Reason = name
+		return status
+	}
+
+	status.Reason = "there is no default storage class, specify one with '--storage-class'"
+	return status
+}
+
+func defaultStorageClass(storageClasses []storagev1.StorageClass) string {
+	for _, storageClass := range storageClasses {
+		if storageClass.Annotations[defaultStorageClassAnnotation] == "true" {
+			return storageClass.Name
+		}
+	}
+	return ""
+}
+
+// checkDataVolumeClaim checks if the persistent volume claim of the control plane data directory is
+// bound, it's skipped if the data directory is in memory.
+func checkDataVolumeClaim(ctx context.Context, kubeClient kubernetes.Interface, namespace string) (*ComponentStatus, error) {
+	pvc, err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, dataVolumeClaimName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ComponentStatus{Name: "storage"}
+	if pvc.Status.Phase != corev1.ClaimBound {
+		status.Reason = fmt.Sprintf("the persistent volume claim %s/%s is not bound", namespace, dataVolumeClaimName)
+		return status, nil
+	}
+
+	status.Healthy = true
+	status.Reason = fmt.Sprintf("the persistent volume claim %s/%s is bound", namespace, dataVolumeClaimName)
+	return status, nil
+}
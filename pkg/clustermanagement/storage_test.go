@@ -0,0 +1,139 @@
+package clustermanagement
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateStorage(t *testing.T) {
+	cases := []struct {
+		name  string
+		opts  StorageOptions
+		valid bool
+	}{
+		{name: "default", valid: true},
+		{name: "persistent", opts: StorageOptions{StorageSize: "10Gi", StorageClass: "gp3"}, valid: true},
+		{name: "invalid size", opts: StorageOptions{StorageSize: "ten"}},
+		{name: "class without size", opts: StorageOptions{StorageClass: "gp3"}},
+		{name: "external etcd", opts: StorageOptions{EtcdServers: []string{"https://etcd:2379"}}, valid: true},
+		{name: "invalid etcd server", opts: StorageOptions{EtcdServers: []string{"etcd:2379"}}},
+		{name: "etcd certs without servers", opts: StorageOptions{EtcdCAFile: "ca.crt"}},
+		{
+			name: "etcd cert without key",
+			opts: StorageOptions{EtcdServers: []string{"https://etcd:2379"}, EtcdCertFile: "client.crt"},
+		},
+		{
+			name:  "replicas",
+			opts:  StorageOptions{Replicas: 3, StorageSize: "10Gi", EtcdServers: []string{"https://etcd:2379"}},
+			valid: true,
+		},
+		{name: "replicas with embedded etcd", opts: StorageOptions{Replicas: 3, StorageSize: "10Gi"}, valid: true},
+		{name: "replicas in memory", opts: StorageOptions{Replicas: 3, EtcdServers: []string{"https://etcd:2379"}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.opts.Validate()
+			if c.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !c.valid && err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestControlPlaneObjectsWithStorage(t *testing.T) {
+	cases := []struct {
+		name       string
+		opts       StorageOptions
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:       "in memory",
+			expected:   []string{"medium: Memory", "--disable-leader-election", "mode: embed"},
+			unexpected: []string{"kind: PersistentVolumeClaim", "type: Recreate"},
+		},
+		{
+			name: "persistent",
+			opts: StorageOptions{StorageSize: "10Gi", StorageClass: "gp3"},
+			expected: []string{
+				"kind: PersistentVolumeClaim", "storageClassName: gp3", "ReadWriteOnce",
+				"claimName: multicluster-controlplane-data", "type: Recreate",
+			},
+			unexpected: []string{"medium: Memory"},
+		},
+		{
+			name: "replicas",
+			opts: StorageOptions{Replicas: 3, StorageSize: "10Gi", EtcdServers: []string{"https://etcd:2379"}},
+			expected: []string{
+				"replicas: 1", "ReadWriteMany", "mode: external", "https://etcd:2379",
+				"name: multicluster-controlplane-connector", "replicas: 2",
+			},
+			unexpected: []string{"--disable-leader-election", "mode: embed", "replicas: 3"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := &controlPlaneDeployer{
+				config: &ControlPlaneConfig{
+					Namespace:         "xcm",
+					Replicas:          c.opts.replicas(),
+					StandbyReplicas:   c.opts.standbyConnectorReplicas(),
+					StorageSize:       c.opts.StorageSize,
+					StorageClass:      c.opts.StorageClass,
+					StorageAccessMode: c.opts.accessMode(),
+					EtcdServers:       c.opts.EtcdServers,
+				},
+			}
+			d.config.OCMConfig = d.renderOCMConfig()
+
+			out := &bytes.Buffer{}
+			if err := (&Manifests{Objects: d.controlPlaneObjects()}).WriteYAML(out); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rendered := out.String() + string(d.config.OCMConfig)
+
+			for _, s := range c.expected {
+				if !strings.Contains(rendered, s) {
+					t.Errorf("expected %q is rendered, but got\n%s", s, rendered)
+				}
+			}
+			for _, s := range c.unexpected {
+				if strings.Contains(rendered, s) {
+					t.Errorf("expected %q is not rendered, but got\n%s", s, rendered)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckStorageClass(t *testing.T) {
+	standard := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "standard",
+			Annotations: map[string]string{defaultStorageClassAnnotation: "true"},
+		},
+	}
+
+	if status := checkStorageClass(context.TODO(), fake.NewSimpleClientset(standard), ""); !status.Healthy {
+		t.Errorf("expected the default storage class is used, but got %v", status)
+	}
+
+	if status := checkStorageClass(context.TODO(), fake.NewSimpleClientset(standard), "gp3"); status.Healthy {
+		t.Errorf("expected the storage class is not found, but got %v", status)
+	}
+
+	if status := checkStorageClass(context.TODO(), fake.NewSimpleClientset(), ""); status.Healthy {
+		t.Errorf("expected no default storage class, but got %v", status)
+	}
+}
@@ -0,0 +1,221 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/skeeey/xcm-cli/pkg/cert"
+	"github.com/skeeey/xcm-cli/pkg/clustermanagement"
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/genericflags"
+	"github.com/skeeey/xcm-cli/pkg/printer"
+)
+
+var args struct {
+	kubeconfig       string
+	controlPlane     string
+	warningThreshold time.Duration
+}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "certs",
+		Short: "Check and rotate the certificates of a control plane",
+		Long: "Check and rotate the certificates of a control plane\n" +
+			"The certificates are the serving certificate of the control plane, the client certificate of its admin " +
+			"kubeconfig and the client certificates of the agents on the clusters that were relayed from this " +
+			"workstation.\n",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, argv []string) {
+			_ = cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(newCheckCmd())
+	cmd.AddCommand(newRotateCmd())
+
+	return cmd
+}
+
+func newCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check the certificates of a control plane",
+		Long: "Check the certificates of a control plane\n" +
+			"The subject, the SANs, the issuer and the remaining validity of each certificate are reported. The " +
+			"command exits with a non-zero code if a certificate expires within the warning threshold or it cannot " +
+			"be checked.\n",
+		Args: cobra.NoArgs,
+		RunE: runCheck,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(
+		&args.controlPlane,
+		"control-plane",
+		"",
+		"The ID of the control plane to check. The default value is the current control plane context.",
+	)
+
+	flags.DurationVar(
+		&args.warningThreshold,
+		"warning-threshold",
+		cert.DefaultWarningThreshold,
+		"A certificate is reported as expiring if its remaining validity is less than the threshold.",
+	)
+
+	printer.AddFlag(flags)
+
+	return cmd
+}
+
+func newRotateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate the certificates of a control plane",
+		Long: "Rotate the certificates of a control plane\n" +
+			"The serving certificate of the control plane and the client certificate of its admin kubeconfig are " +
+			"regenerated with the CA that the cluster was connected with, then the control plane is restarted and " +
+			"the admin kubeconfig is updated. The agents renew their client certificates themselves.\n" +
+			"Only the control planes that were connected with '--ca-cert' or '--ca-secret' can be rotated, a control " +
+			"plane that generates its own CAs keeps their keys in its pod, disconnect the cluster and connect it " +
+			"again to regenerate its certificates.\n",
+		Args: cobra.NoArgs,
+		RunE: runRotate,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(
+		&args.kubeconfig,
+		"kubeconfig",
+		"",
+		"The kubeconfig of the cluster that the xCM connector is deployed on. "+
+			"The default value is the kubeconfig that the cluster was connected with.",
+	)
+
+	flags.StringVar(
+		&args.controlPlane,
+		"control-plane",
+		"",
+		"The ID of the control plane to rotate. The default value is the current control plane context.",
+	)
+
+	genericflags.AddFlag(flags)
+
+	return cmd
+}
+
+func runCheck(cmd *cobra.Command, argv []string) error {
+	ctx := context.Background()
+
+	controlPlaneContext, err := configs.GetControlPlaneContext(args.controlPlane)
+	if err != nil {
+		return err
+	}
+
+	p, err := printer.NewPrinter(printer.Output(), printer.CertificatesTable)
+	if err != nil {
+		return err
+	}
+
+	// the certificates are checked independently, a failed check doesn't block the others
+	errs := []error{}
+	infos := []*cert.CertificateInfo{}
+
+	kubeconfig, err := configs.ControlPlaneKubeConfig(controlPlaneContext.ID)
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		controlPlaneInfos, err := clustermanagement.CheckControlPlaneCertificates(kubeconfig, args.warningThreshold)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		infos = append(infos, controlPlaneInfos...)
+	}
+
+	clusterIDs := []string{}
+	for clusterID := range controlPlaneContext.RelayedClusters {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+	sort.Strings(clusterIDs)
+
+	for _, clusterID := range clusterIDs {
+		info, err := clustermanagement.CheckAgentCertificate(
+			ctx, controlPlaneContext.RelayedClusters[clusterID], clusterID, args.warningThreshold)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	if err := p.Print(os.Stdout, infos); err != nil {
+		return err
+	}
+
+	expiring := 0
+	for _, info := range infos {
+		if info.Status != cert.StatusValid {
+			expiring++
+		}
+	}
+	if expiring > 0 {
+		errs = append(errs, fmt.Errorf("%d of %d certificates expire within %s", expiring, len(infos), args.warningThreshold))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func runRotate(cmd *cobra.Command, argv []string) error {
+	ctx := context.Background()
+
+	controlPlaneContext, err := configs.GetControlPlaneContext(args.controlPlane)
+	if err != nil {
+		return err
+	}
+
+	kubeconfig := args.kubeconfig
+	if kubeconfig == "" {
+		kubeconfig = controlPlaneContext.HostingKubeconfig
+	}
+	if kubeconfig == "" {
+		return fmt.Errorf("the cluster that the control plane %q is connected from is unknown, "+
+			"specify its kubeconfig with '--kubeconfig'", controlPlaneContext.ID)
+	}
+
+	provider := clustermanagement.ContextProvider(controlPlaneContext)
+	deployer, err := clustermanagement.NewDeployer(provider, &clustermanagement.DeployerOptions{
+		KubeconfigPath: kubeconfig,
+		Namespace:      constants.DefaultControlPlaneNamespace,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build %s deployer with %q: %v", provider, kubeconfig, err)
+	}
+
+	rotator, ok := deployer.(clustermanagement.CertificateRotator)
+	if !ok {
+		return fmt.Errorf("the %s provider doesn't support rotating the certificates", provider)
+	}
+
+	fmt.Fprintln(os.Stdout, "Rotate the certificates of the control plane [connector] ...")
+	if err := rotator.RotateCertificates(ctx, os.Stdout); err != nil {
+		return fmt.Errorf("failed to rotate the certificates: %v", err)
+	}
+
+	if args.kubeconfig != "" && args.kubeconfig != controlPlaneContext.HostingKubeconfig {
+		if err := configs.RecordHostingCluster(controlPlaneContext.ID, args.kubeconfig, provider); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(os.Stdout, "The certificates are rotated for the control plane with id", deployer.GetControlPlaneID())
+	return nil
+}
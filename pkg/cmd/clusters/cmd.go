@@ -1,7 +1,7 @@
 package clusters
 
 import (
-	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -17,11 +17,13 @@ func NewCmd() *cobra.Command {
 		Short: "List clusters from xCM",
 		Long: "List clusters from xCM\n" +
 			"`xcm clusters` list all clusters\n" +
-			"`xcm clusters <cluster-id>` list a specified cluster with its id\n",
+			"`xcm clusters <cluster-id>` list a specified cluster with its id\n" +
+			"`xcm clusters -o json` list all clusters in json format\n",
 		Args: cobra.MaximumNArgs(1),
 		RunE: run,
 	}
 
+	printer.AddFlag(cmd.Flags())
 	genericflags.AddFlag(cmd.Flags())
 
 	return cmd
@@ -33,25 +35,28 @@ func run(cmd *cobra.Command, argv []string) error {
 		return err
 	}
 
-	if xcmConfig.AccessToken == "" || xcmConfig.RefreshToken == "" || xcmConfig.URL == "" {
-		return fmt.Errorf("login required")
+	p, err := printer.NewPrinter(printer.Output(), printer.ClustersTable)
+	if err != nil {
+		return err
+	}
+
+	client, err := rest.NewClient(xcmConfig)
+	if err != nil {
+		return err
 	}
 
 	if len(argv) == 0 {
-		clusters, err := rest.GetAllClusters(xcmConfig.URL)
+		clusters, err := client.GetAllClusters()
 		if err != nil {
 			return err
 		}
 
-		printer.PrintClustersTable(clusters...)
-		return nil
-
+		return p.Print(os.Stdout, clusters)
 	}
 
-	cluster, err := rest.GetCluster(xcmConfig.URL, argv[0])
+	cluster, err := client.GetCluster(argv[0])
 	if err != nil {
 		return err
 	}
-	printer.PrintClustersTable(*cluster)
-	return nil
+	return p.Print(os.Stdout, cluster)
 }
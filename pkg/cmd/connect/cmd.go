@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -12,11 +13,26 @@ import (
 	"github.com/skeeey/xcm-cli/pkg/configs"
 	"github.com/skeeey/xcm-cli/pkg/constants"
 	"github.com/skeeey/xcm-cli/pkg/genericflags"
+	"github.com/skeeey/xcm-cli/pkg/rest"
 )
 
+// xcmServerPlaceholder is the URL of the xCM API gateway in the rendered manifests when the user
+// isn't logged in.
+const xcmServerPlaceholder = "XCM_SERVER_URL"
+
 var args struct {
-	kubeconfig  string
-	displayName string
+	kubeconfig    string
+	displayName   string
+	provider      string
+	skipPreflight bool
+	dryRun        string
+	render        bool
+	prune         bool
+	images        clustermanagement.ImageOptions
+	expose        clustermanagement.ExposeOptions
+	storage       clustermanagement.StorageOptions
+	rbac          clustermanagement.RBACOptions
+	ca            clustermanagement.CAOptions
 }
 
 func NewCmd() *cobra.Command {
@@ -49,29 +65,151 @@ func addFlags(flags *pflag.FlagSet) {
 		"A display name for the current cluster. The default value is cluster ID.",
 	)
 
+	flags.StringVar(
+		&args.provider,
+		"provider",
+		clustermanagement.DefaultProvider,
+		fmt.Sprintf("The provider of your cluster. One of: %s.", strings.Join(clustermanagement.Providers(), "|")),
+	)
+
+	flags.BoolVar(
+		&args.skipPreflight,
+		"skip-preflight",
+		false,
+		"Skip the preflight checks, run 'xcm doctor' to run them standalone.",
+	)
+
+	flags.StringVar(
+		&args.dryRun,
+		"dry-run",
+		clustermanagement.DryRunNone,
+		"Must be \"none\", \"client\" or \"server\". If client, only print the objects that would be applied. "+
+			"If server, submit the objects to the server in the dry-run mode without persisting them.",
+	)
+
+	flags.BoolVar(
+		&args.render,
+		"render",
+		false,
+		"Print the rendered objects that would be applied as a multi-document YAML without changing the cluster.",
+	)
+
+	flags.BoolVar(
+		&args.prune,
+		"prune",
+		false,
+		"Delete the objects of the xCM connector that were applied by a previous version of xcm but are no longer rendered. "+
+			"Only the objects that are labeled with 'xcm.open-cluster-management.io/install' are deleted.",
+	)
+
+	clustermanagement.AddControlPlaneImageFlags(flags, &args.images)
+	clustermanagement.AddImageFlags(flags, &args.images)
+	clustermanagement.AddExposeFlags(flags, &args.expose)
+	clustermanagement.AddStorageFlags(flags, &args.storage)
+	clustermanagement.AddRBACFlags(flags, &args.rbac)
+	clustermanagement.AddCAFlags(flags, &args.ca)
 }
 
 func run(cmd *cobra.Command, argv []string) error {
+	if err := clustermanagement.ValidateDryRun(args.dryRun); err != nil {
+		return err
+	}
+
 	apiConfig, err := configs.LoadAPIConfig()
 	if err != nil {
 		return err
 	}
 
-	if apiConfig.AccessToken == "" || apiConfig.RefreshToken == "" || apiConfig.URL == "" {
-		return fmt.Errorf("login required")
+	// the login is required to connect the cluster, but not to render the manifests or to run in the
+	// dry-run mode
+	connecting := !args.render && args.dryRun == clustermanagement.DryRunNone
+	xcmServer, err := xcmServerURL(apiConfig, connecting)
+	if err != nil {
+		return err
 	}
 
 	// TODO configure the namespace with cli
-	eksDeployer, err := clustermanagement.BuildEKSDeployer(
-		args.kubeconfig, constants.DefaultControlPlaneNamespace, apiConfig.URL)
+	deployer, err := clustermanagement.NewDeployer(args.provider, &clustermanagement.DeployerOptions{
+		KubeconfigPath: args.kubeconfig,
+		Namespace:      constants.DefaultControlPlaneNamespace,
+		XCMServer:      xcmServer,
+		ImageOptions:   args.images,
+		ExposeOptions:  args.expose,
+		StorageOptions: args.storage,
+		RBACOptions:    args.rbac,
+		CAOptions:      args.ca,
+		Prune:          args.prune,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to build eks deployer with %q: %v", args.kubeconfig, err)
+		return fmt.Errorf("failed to build %s deployer with %q: %v", args.provider, args.kubeconfig, err)
+	}
+
+	ctx := context.Background()
+	renderer, ok := deployer.(clustermanagement.Renderer)
+	if !ok && (args.render || args.dryRun != clustermanagement.DryRunNone) {
+		return fmt.Errorf("the %s provider doesn't support rendering the manifests", args.provider)
 	}
 
-	if err := eksDeployer.Connect(context.Background()); err != nil {
+	if args.render {
+		manifests, err := renderer.Render(ctx)
+		if err != nil {
+			return err
+		}
+
+		return manifests.WriteYAML(os.Stdout)
+	}
+
+	if preflighter, ok := deployer.(clustermanagement.Preflighter); ok && !args.skipPreflight {
+		if err := clustermanagement.RunPreflight(ctx, os.Stdout, preflighter); err != nil {
+			return err
+		}
+	}
+
+	if args.dryRun != clustermanagement.DryRunNone {
+		return renderer.DryRun(ctx, os.Stdout, args.dryRun)
+	}
+
+	if err := deployer.Connect(ctx); err != nil {
 		return err
 	}
 
-	fmt.Fprintln(os.Stdout, "The cluster is connected to xCM with id", eksDeployer.GetControlPlaneID())
+	// the cluster is recorded, so that the connector can be upgraded later
+	if err := configs.RecordHostingCluster(deployer.GetControlPlaneID(), args.kubeconfig, args.provider); err != nil {
+		return err
+	}
+	if err := configs.RecordExposure(deployer.GetControlPlaneID(), args.expose.Expose, args.expose.ExternalHostname); err != nil {
+		return err
+	}
+	if err := configs.RecordImages(deployer.GetControlPlaneID(), args.images.ContextImages()); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stdout, "The cluster is connected to xCM with id", deployer.GetControlPlaneID())
 	return nil
 }
+
+// xcmServerURL returns the URL of the xCM API gateway that the connector reports to. If the cluster is
+// connected, a rest client is built to ensure the user is logged in, otherwise the configured URL is
+// used, and a placeholder is used if the URL isn't configured.
+func xcmServerURL(apiConfig *configs.APIConfig, connecting bool) (string, error) {
+	if connecting {
+		client, err := rest.NewClient(apiConfig)
+		if err != nil {
+			return "", err
+		}
+
+		return client.URL(), nil
+	}
+
+	url, err := apiConfig.GatewayURL()
+	if err != nil {
+		return "", err
+	}
+	if url == "" {
+		fmt.Fprintf(os.Stderr, "The URL of the xCM API gateway is not configured, "+
+			"replace %s with the URL in the rendered manifests\n", xcmServerPlaceholder)
+		return xcmServerPlaceholder, nil
+	}
+
+	return strings.TrimSuffix(url, "/"), nil
+}
@@ -0,0 +1,106 @@
+package contexts
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/printer"
+)
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "context",
+		Short: "Manage the control plane contexts",
+		Long: "Manage the control plane contexts\n" +
+			"Each connected cluster hosts a control plane, its kubeconfig is saved as a context with the control plane ID.\n" +
+			"The current context is used by `xcm relay` if the '--control-plane' is not specified.\n",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, argv []string) {
+			_ = cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newUseCmd())
+	cmd.AddCommand(newDeleteCmd())
+
+	return cmd
+}
+
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the control plane contexts",
+		Long:  "List the control plane contexts, the current context is marked with '*'.",
+		Args:  cobra.NoArgs,
+		RunE:  runList,
+	}
+
+	printer.AddFlag(cmd.Flags())
+
+	return cmd
+}
+
+func newUseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <control-plane-id>",
+		Short: "Set the current control plane context",
+		Long:  "Set the current control plane context.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runUse,
+	}
+}
+
+func newDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <control-plane-id>",
+		Short: "Delete a control plane context",
+		Long:  "Delete a control plane context and its kubeconfig, the control plane is not changed.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runDelete,
+	}
+}
+
+func runList(cmd *cobra.Command, argv []string) error {
+	contexts, err := configs.LoadControlPlaneContexts()
+	if err != nil {
+		return err
+	}
+
+	p, err := printer.NewPrinter(printer.Output(), printer.ContextsTable(contexts.CurrentContext))
+	if err != nil {
+		return err
+	}
+
+	return p.Print(os.Stdout, contexts.List())
+}
+
+func runUse(cmd *cobra.Command, argv []string) error {
+	if err := configs.UseControlPlaneContext(argv[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Switched to control plane context %q\n", argv[0])
+	return nil
+}
+
+func runDelete(cmd *cobra.Command, argv []string) error {
+	contexts, err := configs.LoadControlPlaneContexts()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := contexts.Contexts[argv[0]]; !ok {
+		return fmt.Errorf("the control plane context %q is not found", argv[0])
+	}
+
+	if err := configs.DeleteControlPlaneContext(argv[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Deleted control plane context %q\n", argv[0])
+	return nil
+}
@@ -0,0 +1,139 @@
+package disconnect
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/skeeey/xcm-cli/pkg/clustermanagement"
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/genericflags"
+	"github.com/skeeey/xcm-cli/pkg/rest"
+)
+
+var args struct {
+	kubeconfig   string
+	controlPlane string
+}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "disconnect",
+		Short: "Disconnect a specified cluster from xCM",
+		Long: "Disconnect a specified cluster from xCM\n" +
+			"If the cluster is connected, the xCM connector is removed from the cluster.\n" +
+			"If the cluster is relayed, the agent is removed from the cluster and the cluster is removed from the control plane.\n",
+		Args: cobra.NoArgs,
+		RunE: run,
+	}
+
+	addFlags(cmd.Flags())
+	genericflags.AddFlag(cmd.Flags())
+
+	return cmd
+}
+
+func addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(
+		&args.kubeconfig,
+		"kubeconfig",
+		"",
+		"The kubeconfig of your cluster.",
+	)
+
+	flags.StringVar(
+		&args.controlPlane,
+		"control-plane",
+		"",
+		"The ID of the control plane that the cluster was relayed to. The default value is the current control plane context.",
+	)
+}
+
+func run(cmd *cobra.Command, argv []string) error {
+	ctx := context.Background()
+
+	apiConfig, err := configs.LoadAPIConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := rest.NewClient(apiConfig)
+	if err != nil {
+		return err
+	}
+
+	// the cluster is connected with the default provider and exposure if it's not recorded
+	hostingContext, err := configs.HostingClusterContext(args.kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	provider := clustermanagement.ContextProvider(hostingContext)
+	deployer, err := clustermanagement.NewDeployer(provider, &clustermanagement.DeployerOptions{
+		KubeconfigPath: args.kubeconfig,
+		Namespace:      constants.DefaultControlPlaneNamespace,
+		ExposeOptions:  clustermanagement.ContextExposeOptions(hostingContext),
+		XCMServer:      client.URL(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build %s deployer with %q: %v", provider, args.kubeconfig, err)
+	}
+
+	connected, err := deployer.Connected(ctx)
+	if err != nil {
+		return err
+	}
+
+	if connected {
+		if err := deployer.Disconnect(ctx); err != nil {
+			return err
+		}
+
+		return deregister(client, deployer.GetControlPlaneID())
+	}
+
+	spokeDeployer, err := clustermanagement.BuildSpokeDeployer(&clustermanagement.SpokeDeployerOptions{
+		KubeconfigPath: args.kubeconfig,
+		ControlPlane:   args.controlPlane,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build spoke deployer with %q: %v", args.kubeconfig, err)
+	}
+
+	relayed, err := spokeDeployer.Relayed(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !relayed {
+		return fmt.Errorf("the cluster is not connected to xCM")
+	}
+
+	if err := spokeDeployer.Disconnect(ctx); err != nil {
+		return err
+	}
+
+	if err := configs.ForgetRelayedCluster(args.controlPlane, spokeDeployer.GetClusterID()); err != nil {
+		return err
+	}
+
+	return deregister(client, spokeDeployer.GetClusterID())
+}
+
+func deregister(client *rest.Client, clusterID string) error {
+	if clusterID == "" {
+		fmt.Fprintln(os.Stdout, "The cluster is disconnected from xCM")
+		return nil
+	}
+
+	if err := client.DeleteCluster(clusterID); err != nil {
+		return fmt.Errorf("failed to deregister the cluster from xCM: %v", err)
+	}
+
+	fmt.Fprintln(os.Stdout, "The cluster is disconnected from xCM with id", clusterID)
+	return nil
+}
@@ -0,0 +1,124 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/skeeey/xcm-cli/pkg/clustermanagement"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/genericflags"
+)
+
+var args struct {
+	kubeconfig   string
+	provider     string
+	relay        bool
+	controlPlane string
+	images       clustermanagement.ImageOptions
+	expose       clustermanagement.ExposeOptions
+	storage      clustermanagement.StorageOptions
+	rbac         clustermanagement.RBACOptions
+	ca           clustermanagement.CAOptions
+}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check if a specified cluster can be connected or relayed to xCM",
+		Long: "Check if a specified cluster can be connected or relayed to xCM\n" +
+			"The preflight checks of 'xcm connect' are run by default, use '--relay' to run the preflight checks of 'xcm relay'.\n" +
+			"The permissions of current user, the target namespace, the cluster claim CRD and the images are checked, " +
+			"and the load balancer and the storage class are checked for 'xcm connect' when they are used. All the problems are reported at once.\n",
+		Args: cobra.NoArgs,
+		RunE: run,
+	}
+
+	addFlags(cmd.Flags())
+	genericflags.AddFlag(cmd.Flags())
+
+	return cmd
+}
+
+func addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(
+		&args.kubeconfig,
+		"kubeconfig",
+		"",
+		"The kubeconfig of your cluster.",
+	)
+
+	flags.StringVar(
+		&args.provider,
+		"provider",
+		clustermanagement.DefaultProvider,
+		fmt.Sprintf("The provider of your cluster. One of: %s.", strings.Join(clustermanagement.Providers(), "|")),
+	)
+
+	flags.BoolVar(
+		&args.relay,
+		"relay",
+		false,
+		"Run the preflight checks of relaying the cluster instead of connecting the cluster.",
+	)
+
+	flags.StringVar(
+		&args.controlPlane,
+		"control-plane",
+		"",
+		"The ID of the control plane that the cluster is relayed to. The default value is the current control plane context.",
+	)
+
+	clustermanagement.AddControlPlaneImageFlags(flags, &args.images)
+	clustermanagement.AddAgentImageFlags(flags, &args.images)
+	clustermanagement.AddImageFlags(flags, &args.images)
+	clustermanagement.AddExposeFlags(flags, &args.expose)
+	clustermanagement.AddStorageFlags(flags, &args.storage)
+	clustermanagement.AddRBACFlags(flags, &args.rbac)
+	clustermanagement.AddCAFlags(flags, &args.ca)
+}
+
+func run(cmd *cobra.Command, argv []string) error {
+	var preflighter clustermanagement.Preflighter
+	if args.relay {
+		spokeDeployer, err := clustermanagement.BuildSpokeDeployer(&clustermanagement.SpokeDeployerOptions{
+			KubeconfigPath: args.kubeconfig,
+			ControlPlane:   args.controlPlane,
+			ImageOptions:   args.images,
+			RBACOptions:    args.rbac,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to build spoke deployer with %q: %v", args.kubeconfig, err)
+		}
+		preflighter = spokeDeployer
+	} else {
+		deployer, err := clustermanagement.NewDeployer(args.provider, &clustermanagement.DeployerOptions{
+			KubeconfigPath: args.kubeconfig,
+			Namespace:      constants.DefaultControlPlaneNamespace,
+			ImageOptions:   args.images,
+			ExposeOptions:  args.expose,
+			StorageOptions: args.storage,
+			RBACOptions:    args.rbac,
+			CAOptions:      args.ca,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to build %s deployer with %q: %v", args.provider, args.kubeconfig, err)
+		}
+
+		var ok bool
+		if preflighter, ok = deployer.(clustermanagement.Preflighter); !ok {
+			return fmt.Errorf("the %s provider doesn't support the preflight checks", args.provider)
+		}
+	}
+
+	statuses, err := preflighter.Preflight(context.Background())
+	if err != nil {
+		return err
+	}
+
+	return clustermanagement.PrintStatuses(os.Stdout, statuses)
+}
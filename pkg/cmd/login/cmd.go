@@ -1,8 +1,11 @@
 package login
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -11,24 +14,36 @@ import (
 
 	"github.com/skeeey/xcm-cli/pkg/configs"
 	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/credstore"
 	"github.com/skeeey/xcm-cli/pkg/genericflags"
 	"github.com/skeeey/xcm-cli/pkg/helpers"
+	"github.com/skeeey/xcm-cli/pkg/oauth"
+	"github.com/skeeey/xcm-cli/pkg/rest"
 )
 
 var args struct {
-	url      string
-	tokenURL string
-	token    string
-	insecure bool
+	url           string
+	tokenURL      string
+	token         string
+	clientID      string
+	clientSecret  string
+	useDeviceCode bool
+	insecure      bool
 }
 
 func NewCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "login",
 		Short: "Log in",
-		Long: "Log in, saving the credentials to the configuration file.\n" +
+		Long: "Log in, saving the settings to the configuration file of the active profile.\n" +
+			"The tokens are saved to the OS keyring, or an encrypted file when the keyring isn't available, set the '" +
+			credstore.BackendEnv + "' environment variable to choose the credential store and the '" +
+			credstore.PassphraseEnv + "' environment variable to give the passphrase of the encrypted file.\n" +
+			"Use the '--profile' flag to log in to different xCM API gateways.\n" +
 			"The recommend way is using '--token', which you can obtain at: " +
-			constants.OfflineTokenPage,
+			constants.OfflineTokenPage + "\n" +
+			"Service accounts can log in with '--client-id' and '--client-secret'.\n" +
+			"Without a browser on this machine, use '--use-device-code' and open the printed URL on another device.",
 		Args: cobra.NoArgs,
 		RunE: run,
 	}
@@ -44,7 +59,8 @@ func addFlags(flags *pflag.FlagSet) {
 		&args.url,
 		"url",
 		sdk.DefaultURL,
-		"URL of the xCM API gateway.",
+		"URL of the xCM API gateway. The value can be the complete URL or an alias. The valid aliases are "+
+			aliasNames()+", the user-defined aliases are loaded from the 'aliases.json' file of the configuration directory.",
 	)
 
 	flags.StringVar(
@@ -60,16 +76,73 @@ func addFlags(flags *pflag.FlagSet) {
 		"",
 		fmt.Sprintf("Red Hat user API token which you can obtain at '%s'.", constants.OfflineTokenPage),
 	)
+
+	flags.StringVar(
+		&args.clientID,
+		"client-id",
+		"",
+		fmt.Sprintf("OpenID client identifier. It is used with '--client-secret' for service accounts, "+
+			"or with '--use-device-code', the default value of the device code login is '%s'.", sdk.DefaultClientID),
+	)
+
+	flags.StringVar(
+		&args.clientSecret,
+		"client-secret",
+		"",
+		"OpenID client secret of the service account.",
+	)
+
+	flags.BoolVar(
+		&args.useDeviceCode,
+		"use-device-code",
+		false,
+		"Log in with the OAuth device authorization flow, a verification URL is printed to open in a browser.",
+	)
+
+	flags.BoolVar(
+		&args.insecure,
+		"insecure",
+		false,
+		"Enables insecure communication with the server. This disables verification of TLS certificates and host names.",
+	)
+}
+
+// aliasNames returns the quoted names of the available aliases of the xCM API gateway URL, the
+// built-in aliases are returned if the aliases file cannot be loaded.
+func aliasNames() string {
+	names, err := configs.URLAliasNames()
+	if err != nil {
+		names = []string{"integration", "production", "staging"}
+	}
+
+	quoted := []string{}
+	for _, name := range names {
+		quoted = append(quoted, fmt.Sprintf("'%s'", name))
+	}
+
+	return strings.Join(quoted, ", ")
 }
 
 func run(cmd *cobra.Command, argv []string) error {
-	if err := helpers.ValidateURL(args.url); err != nil {
+	url, aliasTokenURL, err := configs.ResolveURL(args.url)
+	if err != nil {
 		return err
 	}
 
+	if err := helpers.ValidateURL(url); err != nil {
+		return err
+	}
+
+	// The token URL of the alias is used unless the token URL is specified explicitly:
+	tokenURL := args.tokenURL
+	if aliasTokenURL != "" && !cmd.Flags().Changed("token-url") {
+		tokenURL = aliasTokenURL
+	}
+
 	haveToken := args.token != ""
-	if !haveToken {
-		return fmt.Errorf("flag '--token' is mandatory")
+	haveCredentials := args.clientID != "" && args.clientSecret != ""
+	if !haveToken && !haveCredentials && !args.useDeviceCode {
+		return fmt.Errorf("one of flag '--token', flags '--client-id' and '--client-secret' or flag '--use-device-code' is mandatory")
 	}
 
 	// Load the configuration file:
@@ -78,7 +151,58 @@ func run(cmd *cobra.Command, argv []string) error {
 		return fmt.Errorf("cannot load config file: %v", err)
 	}
 
-	if haveToken {
+	cfg.ClientID = ""
+	cfg.ClientSecret = ""
+
+	ctx := context.Background()
+	// the OpenID server is verified in the same way as the xCM API gateway
+	httpClient := &http.Client{
+		Transport: rest.NewTransport(args.insecure),
+		Timeout:   genericflags.TimeOut(),
+	}
+
+	switch {
+	case args.useDeviceCode:
+		clientID := args.clientID
+		if clientID == "" {
+			clientID = sdk.DefaultClientID
+		}
+
+		authorization, err := oauth.RequestDeviceAuthorization(
+			ctx, httpClient, oauth.DeviceAuthorizationURL(tokenURL), clientID, sdk.DefaultScopes)
+		if err != nil {
+			return err
+		}
+
+		if authorization.VerificationURIComplete != "" {
+			fmt.Fprintf(os.Stdout, "To log in, open the URL %s\n", authorization.VerificationURIComplete)
+		} else {
+			fmt.Fprintf(os.Stdout, "To log in, open the URL %s and enter the code %s\n",
+				authorization.VerificationURI, authorization.UserCode)
+		}
+
+		token, err := oauth.PollDeviceToken(ctx, httpClient, tokenURL, clientID, authorization)
+		if err != nil {
+			return err
+		}
+
+		// The refresh token is bound to the client, keep the client to refresh the tokens:
+		cfg.ClientID = clientID
+		cfg.AccessToken = token.AccessToken
+		cfg.RefreshToken = token.RefreshToken
+	case haveCredentials:
+		token, err := oauth.ClientCredentials(ctx, httpClient, tokenURL, args.clientID, args.clientSecret, sdk.DefaultScopes)
+		if err != nil {
+			return err
+		}
+
+		// There is no refresh token with the client credentials, keep the credentials to request
+		// new tokens:
+		cfg.ClientID = args.clientID
+		cfg.ClientSecret = args.clientSecret
+		cfg.AccessToken = token.AccessToken
+		cfg.RefreshToken = token.RefreshToken
+	default:
 		// Encrypted tokens are assumed to be refresh tokens:
 		if configs.IsEncryptedToken(args.token) {
 			cfg.AccessToken = ""
@@ -108,7 +232,7 @@ func run(cmd *cobra.Command, argv []string) error {
 	}
 
 	// Update the configuration with the values given in the command line:
-	cfg.TokenURL = args.tokenURL
+	cfg.TokenURL = tokenURL
 	cfg.Scopes = sdk.DefaultScopes //TODO ??
 	cfg.URL = args.url
 	cfg.Insecure = args.insecure
@@ -132,6 +256,6 @@ func run(cmd *cobra.Command, argv []string) error {
 		return fmt.Errorf("cannot save config file: %v", err)
 	}
 
-	fmt.Fprintln(os.Stdout, "Login successful")
+	fmt.Fprintf(os.Stdout, "Login successful with profile %q\n", configs.ActiveProfile())
 	return nil
 }
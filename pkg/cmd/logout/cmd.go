@@ -13,7 +13,7 @@ func NewCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "logout",
 		Short: "Log out",
-		Long:  "Log out, removing connection related variables from the config file.",
+		Long:  "Log out, removing connection related variables from the config file and the credential store of the active profile.",
 		Args:  cobra.NoArgs,
 		RunE:  run,
 	}
@@ -4,16 +4,27 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/skeeey/xcm-cli/pkg/clustermanagement"
+	"github.com/skeeey/xcm-cli/pkg/configs"
 	"github.com/skeeey/xcm-cli/pkg/genericflags"
 )
 
 var args struct {
-	kubeconfig string
+	kubeconfig      string
+	controlPlane    string
+	forceReregister bool
+	bootstrapTTL    time.Duration
+	skipPreflight   bool
+	dryRun          string
+	render          bool
+	prune           bool
+	images          clustermanagement.ImageOptions
+	rbac            clustermanagement.RBACOptions
 }
 
 func NewCmd() *cobra.Command {
@@ -39,15 +50,109 @@ func addFlags(flags *pflag.FlagSet) {
 		"The kubeconfig of your cluster",
 	)
 
+	flags.StringVar(
+		&args.controlPlane,
+		"control-plane",
+		"",
+		"The ID of the control plane that the cluster is relayed to. The default value is the current control plane context.",
+	)
+
+	flags.BoolVar(
+		&args.forceReregister,
+		"force-reregister",
+		false,
+		"Register the cluster again if it is registered by another cluster or it was relayed to a different control plane.",
+	)
+
+	flags.DurationVar(
+		&args.bootstrapTTL,
+		"bootstrap-token-ttl",
+		clustermanagement.DefaultBootstrapTokenTTL,
+		"The lifetime of the token that the agent bootstraps with, the agent is bound to a role on the control plane "+
+			"that can only create its certificate signing requests and its managed cluster. "+
+			"Relay the cluster again if the agent needs to bootstrap after the token expired.",
+	)
+
+	flags.BoolVar(
+		&args.skipPreflight,
+		"skip-preflight",
+		false,
+		"Skip the preflight checks, run 'xcm doctor' to run them standalone.",
+	)
+
+	flags.StringVar(
+		&args.dryRun,
+		"dry-run",
+		clustermanagement.DryRunNone,
+		"Must be \"none\", \"client\" or \"server\". If client, only print the objects that would be applied. "+
+			"If server, submit the objects to the server in the dry-run mode without persisting them.",
+	)
+
+	flags.BoolVar(
+		&args.render,
+		"render",
+		false,
+		"Print the rendered objects that would be applied as a multi-document YAML without changing the cluster.",
+	)
+
+	flags.BoolVar(
+		&args.prune,
+		"prune",
+		false,
+		"Delete the objects of the agent that were applied by a previous version of xcm but are no longer rendered. "+
+			"Only the objects that are labeled with 'xcm.open-cluster-management.io/install' are deleted.",
+	)
+
+	clustermanagement.AddAgentImageFlags(flags, &args.images)
+	clustermanagement.AddImageFlags(flags, &args.images)
+	clustermanagement.AddRBACFlags(flags, &args.rbac)
 }
 
 func run(cmd *cobra.Command, argv []string) error {
-	spokeDeployer, err := clustermanagement.BuildSpokeDeployer(args.kubeconfig)
+	if err := clustermanagement.ValidateDryRun(args.dryRun); err != nil {
+		return err
+	}
+
+	spokeDeployer, err := clustermanagement.BuildSpokeDeployer(&clustermanagement.SpokeDeployerOptions{
+		KubeconfigPath:    args.kubeconfig,
+		ControlPlane:      args.controlPlane,
+		ForceReregister:   args.forceReregister,
+		BootstrapTokenTTL: args.bootstrapTTL,
+		ImageOptions:      args.images,
+		RBACOptions:       args.rbac,
+		Prune:             args.prune,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to build spoke deployer with %q: %v", args.kubeconfig, err)
 	}
 
-	if err := spokeDeployer.Relay(context.Background()); err != nil {
+	ctx := context.Background()
+	if args.render {
+		manifests, err := spokeDeployer.Render(ctx)
+		if err != nil {
+			return err
+		}
+
+		return manifests.WriteYAML(os.Stdout)
+	}
+
+	if !args.skipPreflight {
+		if err := clustermanagement.RunPreflight(ctx, os.Stdout, spokeDeployer); err != nil {
+			return err
+		}
+	}
+
+	if args.dryRun != clustermanagement.DryRunNone {
+		return spokeDeployer.DryRun(ctx, os.Stdout, args.dryRun)
+	}
+
+	if err := spokeDeployer.Relay(ctx); err != nil {
+		return err
+	}
+
+	// the cluster is recorded, so that the agent can be upgraded later
+	if err := configs.RecordRelayedCluster(args.controlPlane, spokeDeployer.GetClusterID(), args.kubeconfig,
+		args.images.ContextImages()); err != nil {
 		return err
 	}
 
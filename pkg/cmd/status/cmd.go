@@ -0,0 +1,159 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/skeeey/xcm-cli/pkg/clustermanagement"
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/genericflags"
+	"github.com/skeeey/xcm-cli/pkg/rest"
+)
+
+var args struct {
+	kubeconfig   string
+	controlPlane string
+}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the health of the connection between a specified cluster and xCM",
+		Long: "Show the health of the connection between a specified cluster and xCM\n" +
+			"If the cluster is connected, the xCM connector, the control plane kubeconfig and the load balancer are checked.\n" +
+			"If the cluster is relayed, the agent and the managed cluster on the control plane are checked.\n" +
+			"The cluster in the xCM inventory is checked in both cases, and the command fails if any component is unhealthy.\n",
+		Args: cobra.NoArgs,
+		RunE: run,
+	}
+
+	addFlags(cmd.Flags())
+	genericflags.AddFlag(cmd.Flags())
+
+	return cmd
+}
+
+func addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(
+		&args.kubeconfig,
+		"kubeconfig",
+		"",
+		"The kubeconfig of your cluster.",
+	)
+
+	flags.StringVar(
+		&args.controlPlane,
+		"control-plane",
+		"",
+		"The ID of the control plane that the cluster is relayed to. The default value is the current control plane context.",
+	)
+}
+
+func run(cmd *cobra.Command, argv []string) error {
+	ctx := context.Background()
+
+	apiConfig, err := configs.LoadAPIConfig()
+	if err != nil {
+		return err
+	}
+
+	// the cluster status is reported without login, the inventory is reported as unhealthy instead
+	client, clientErr := rest.NewClient(apiConfig)
+	xcmServer := ""
+	if clientErr == nil {
+		xcmServer = client.URL()
+	}
+
+	// the cluster is connected with the default provider and exposure if it's not recorded
+	hostingContext, err := configs.HostingClusterContext(args.kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	provider := clustermanagement.ContextProvider(hostingContext)
+	deployer, err := clustermanagement.NewDeployer(provider, &clustermanagement.DeployerOptions{
+		KubeconfigPath: args.kubeconfig,
+		Namespace:      constants.DefaultControlPlaneNamespace,
+		ExposeOptions:  clustermanagement.ContextExposeOptions(hostingContext),
+		XCMServer:      xcmServer,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build %s deployer with %q: %v", provider, args.kubeconfig, err)
+	}
+
+	connected, err := deployer.Connected(ctx)
+	if err != nil {
+		return err
+	}
+
+	var statuses []clustermanagement.ComponentStatus
+	var clusterID string
+	if connected {
+		statuses, err = deployer.Status(ctx)
+		if err != nil {
+			return err
+		}
+		clusterID = deployer.GetControlPlaneID()
+	} else {
+		spokeDeployer, err := clustermanagement.BuildSpokeDeployer(&clustermanagement.SpokeDeployerOptions{
+			KubeconfigPath: args.kubeconfig,
+			ControlPlane:   args.controlPlane,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to build spoke deployer with %q: %v", args.kubeconfig, err)
+		}
+
+		relayed, err := spokeDeployer.Relayed(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !relayed {
+			return fmt.Errorf("the cluster is not connected to xCM")
+		}
+
+		statuses, err = spokeDeployer.Status(ctx)
+		if err != nil {
+			return err
+		}
+		clusterID = spokeDeployer.GetClusterID()
+	}
+
+	statuses = append(statuses, inventoryStatus(client, clientErr, clusterID))
+
+	if clusterID != "" {
+		fmt.Fprintln(os.Stdout, "Cluster", clusterID)
+	}
+
+	return clustermanagement.PrintStatuses(os.Stdout, statuses)
+}
+
+// inventoryStatus checks if the cluster is registered and available in the xCM inventory.
+func inventoryStatus(client *rest.Client, clientErr error, clusterID string) clustermanagement.ComponentStatus {
+	inventory := clustermanagement.ComponentStatus{Name: "inventory"}
+
+	if clientErr != nil {
+		inventory.Reason = clientErr.Error()
+		return inventory
+	}
+
+	if clusterID == "" {
+		inventory.Reason = "the cluster id is not found"
+		return inventory
+	}
+
+	cluster, err := client.GetCluster(clusterID)
+	if err != nil {
+		inventory.Reason = err.Error()
+		return inventory
+	}
+
+	inventory.Healthy = cluster.Status == rest.ClusterStatusAvailable
+	inventory.Reason = cluster.Status
+	return inventory
+}
@@ -0,0 +1,168 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/skeeey/xcm-cli/pkg/clustermanagement"
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/genericflags"
+)
+
+var args struct {
+	kubeconfig   string
+	controlPlane string
+	images       clustermanagement.ImageOptions
+}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade the xCM connector and the agents of a control plane",
+		Long: "Upgrade the xCM connector and the agents of a control plane\n" +
+			"The connector is upgraded on the cluster that it was connected from, and the agents are upgraded on the " +
+			"clusters that were relayed from this workstation. Each deployment is rolled back to its previous revision " +
+			"if it fails to roll out.\n" +
+			"The images and the image registry that the components were deployed with are kept unless they're given again.\n",
+		Args: cobra.NoArgs,
+		RunE: run,
+	}
+
+	addFlags(cmd.Flags())
+	genericflags.AddFlag(cmd.Flags())
+
+	return cmd
+}
+
+func addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(
+		&args.images.Version,
+		"to",
+		"",
+		"The version to upgrade to, it replaces the tag of the default images. "+
+			"The default images of this version of xcm are used by default.",
+	)
+
+	flags.StringVar(
+		&args.kubeconfig,
+		"kubeconfig",
+		"",
+		"The kubeconfig of the cluster that the xCM connector is deployed on. "+
+			"The default value is the kubeconfig that the cluster was connected with.",
+	)
+
+	flags.StringVar(
+		&args.controlPlane,
+		"control-plane",
+		"",
+		"The ID of the control plane to upgrade. The default value is the current control plane context.",
+	)
+
+	clustermanagement.AddControlPlaneImageFlags(flags, &args.images)
+	clustermanagement.AddAgentImageFlags(flags, &args.images)
+	clustermanagement.AddImageFlags(flags, &args.images)
+}
+
+func run(cmd *cobra.Command, argv []string) error {
+	ctx := context.Background()
+
+	controlPlaneContext, err := configs.GetControlPlaneContext(args.controlPlane)
+	if err != nil {
+		return err
+	}
+
+	kubeconfig := args.kubeconfig
+	if kubeconfig == "" {
+		kubeconfig = controlPlaneContext.HostingKubeconfig
+	}
+	if kubeconfig == "" {
+		return fmt.Errorf("the cluster that the control plane %q is connected from is unknown, "+
+			"specify its kubeconfig with '--kubeconfig'", controlPlaneContext.ID)
+	}
+
+	// the images that the connector was deployed with are kept unless they're given again, the agent
+	// images are recorded per relayed cluster
+	connectorImages := args.images
+	connectorImages.AgentImage = ""
+	images := clustermanagement.ContextImageOptions(controlPlaneContext.Images, connectorImages)
+	provider := clustermanagement.ContextProvider(controlPlaneContext)
+	deployer, err := clustermanagement.NewDeployer(provider, &clustermanagement.DeployerOptions{
+		KubeconfigPath: kubeconfig,
+		Namespace:      constants.DefaultControlPlaneNamespace,
+		ImageOptions:   images,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build %s deployer with %q: %v", provider, kubeconfig, err)
+	}
+
+	upgrader, ok := deployer.(clustermanagement.Upgrader)
+	if !ok {
+		return fmt.Errorf("the %s provider doesn't support upgrading the connector", provider)
+	}
+
+	fmt.Fprintln(os.Stdout, "Upgrade the xCM connector [connector] ...")
+	if err := upgrader.Upgrade(ctx, os.Stdout); err != nil {
+		return fmt.Errorf("failed to upgrade the connector: %v", err)
+	}
+	if err := configs.RecordImages(controlPlaneContext.ID, images.ContextImages()); err != nil {
+		return err
+	}
+
+	if args.kubeconfig != "" && args.kubeconfig != controlPlaneContext.HostingKubeconfig {
+		if err := configs.RecordHostingCluster(controlPlaneContext.ID, args.kubeconfig, provider); err != nil {
+			return err
+		}
+	}
+
+	agentImages := args.images
+	agentImages.ControlPlaneImage = ""
+	agentImages.ConnectorImage = ""
+
+	clusterIDs := []string{}
+	for clusterID := range controlPlaneContext.RelayedClusters {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+	sort.Strings(clusterIDs)
+
+	// the agents are upgraded independently, a failed agent doesn't block the others
+	errs := []error{}
+	for _, clusterID := range clusterIDs {
+		spokeKubeconfig := controlPlaneContext.RelayedClusters[clusterID]
+		spokeImages := clustermanagement.ContextImageOptions(controlPlaneContext.RelayedClusterImages[clusterID], agentImages)
+		fmt.Fprintf(os.Stdout, "Upgrade the agent of cluster %s [agent] ...\n", clusterID)
+		spokeDeployer, err := clustermanagement.BuildSpokeDeployer(&clustermanagement.SpokeDeployerOptions{
+			KubeconfigPath: spokeKubeconfig,
+			ControlPlane:   controlPlaneContext.ID,
+			ImageOptions:   spokeImages,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to build spoke deployer with %q: %v", spokeKubeconfig, err))
+			continue
+		}
+
+		if err := spokeDeployer.Upgrade(ctx, os.Stdout); err != nil {
+			errs = append(errs, fmt.Errorf("failed to upgrade the agent of cluster %s: %v", clusterID, err))
+			continue
+		}
+
+		if err := configs.RecordRelayedCluster(controlPlaneContext.ID, clusterID, spokeKubeconfig,
+			spokeImages.ContextImages()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stdout, "The control plane is upgraded with id", deployer.GetControlPlaneID())
+	return nil
+}
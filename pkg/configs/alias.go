@@ -0,0 +1,116 @@
+package configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+)
+
+const urlAliasesFileName = "aliases.json"
+
+// URLAlias is an alias of the xCM API gateway URL and its OpenID token URL.
+type URLAlias struct {
+	URL      string `json:"url"`
+	TokenURL string `json:"token_url,omitempty"`
+}
+
+// builtinURLAliases are the aliases that are always available, the user-defined aliases with
+// the same name take precedence.
+var builtinURLAliases = map[string]URLAlias{
+	"production": {
+		URL:      "https://api.openshift.com",
+		TokenURL: sdk.DefaultTokenURL,
+	},
+	"staging": {
+		URL:      "https://api.stage.openshift.com",
+		TokenURL: sdk.DefaultTokenURL,
+	},
+	"integration": {
+		URL:      "https://api.integration.openshift.com",
+		TokenURL: sdk.DefaultTokenURL,
+	},
+}
+
+// LoadURLAliases returns the built-in aliases and the user-defined aliases, the user-defined aliases
+// are loaded from the 'aliases.json' file in the configuration directory, e.g.
+//
+//	{
+//	  "local": {
+//	    "url": "http://localhost:8000",
+//	    "token_url": "http://localhost:8080/token"
+//	  }
+//	}
+func LoadURLAliases() (map[string]URLAlias, error) {
+	aliases := map[string]URLAlias{}
+	for name, alias := range builtinURLAliases {
+		aliases[name] = alias
+	}
+
+	dir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	file := filepath.Join(dir, urlAliasesFileName)
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return aliases, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("canot read aliases file '%s': %v", file, err)
+	}
+
+	if len(data) == 0 {
+		return aliases, nil
+	}
+
+	userAliases := map[string]URLAlias{}
+	if err := json.Unmarshal(data, &userAliases); err != nil {
+		return nil, fmt.Errorf("can't parse aliases file '%s': %v", file, err)
+	}
+
+	for name, alias := range userAliases {
+		if alias.URL == "" {
+			return nil, fmt.Errorf("the url of alias '%s' in the aliases file '%s' isn't set", name, file)
+		}
+		aliases[name] = alias
+	}
+
+	return aliases, nil
+}
+
+// URLAliasNames returns the sorted names of the available aliases.
+func URLAliasNames() ([]string, error) {
+	aliases, err := LoadURLAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// ResolveURL resolves the given value to the URL of the xCM API gateway and its token URL. If the
+// value is not an alias, the value is returned as the URL and the token URL is empty.
+func ResolveURL(value string) (url string, tokenURL string, err error) {
+	aliases, err := LoadURLAliases()
+	if err != nil {
+		return "", "", err
+	}
+
+	alias, ok := aliases[value]
+	if !ok {
+		return value, "", nil
+	}
+
+	return alias.URL, alias.TokenURL, nil
+}
@@ -0,0 +1,73 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+)
+
+func TestResolveURL(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	url, tokenURL, err := ResolveURL("staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "https://api.stage.openshift.com" || tokenURL != sdk.DefaultTokenURL {
+		t.Errorf("unexpected url %q and token url %q", url, tokenURL)
+	}
+
+	url, tokenURL, err = ResolveURL("https://xcm.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "https://xcm.example.com" || tokenURL != "" {
+		t.Errorf("unexpected url %q and token url %q", url, tokenURL)
+	}
+
+	dir, err := ConfigDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, urlAliasesFileName), []byte(`{
+  "local": {"url": "http://localhost:8000", "token_url": "http://localhost:8080/token"},
+  "staging": {"url": "https://xcm.stage.example.com"}
+}`), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	url, tokenURL, err = ResolveURL("local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "http://localhost:8000" || tokenURL != "http://localhost:8080/token" {
+		t.Errorf("unexpected url %q and token url %q", url, tokenURL)
+	}
+
+	url, _, err = ResolveURL("staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "https://xcm.stage.example.com" {
+		t.Errorf("expected the user-defined alias takes precedence, but got %q", url)
+	}
+
+	names, err := URLAliasNames()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(names, ",") != "integration,local,production,staging" {
+		t.Errorf("unexpected alias names %v", names)
+	}
+
+	gatewayURL, err := (&APIConfig{URL: "local"}).GatewayURL()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gatewayURL != "http://localhost:8000" {
+		t.Errorf("unexpected gateway url %q", gatewayURL)
+	}
+}
@@ -2,6 +2,7 @@ package configs
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,27 +11,68 @@ import (
 	"github.com/golang/glog"
 	sdk "github.com/openshift-online/ocm-sdk-go"
 
+	"github.com/skeeey/xcm-cli/pkg/credstore"
 	"github.com/skeeey/xcm-cli/pkg/genericflags"
 	"github.com/skeeey/xcm-cli/pkg/info"
 )
 
 type APIConfig struct {
+	ClientID     string   `json:"client_id,omitempty" doc:"OpenID client identifier."`
+	ClientSecret string   `json:"client_secret,omitempty" doc:"OpenID client secret."`
 	AccessToken  string   `json:"access_token,omitempty" doc:"Bearer access token."`
 	RefreshToken string   `json:"refresh_token,omitempty" doc:"Offline or refresh token."`
 	Scopes       []string `json:"scopes,omitempty" doc:"OpenID scope. If this option is used it will replace completely the default scopes. Can be repeated multiple times to specify multiple scopes."`
 	TokenURL     string   `json:"token_url,omitempty" doc:"OpenID token URL."`
-	URL          string   `json:"url,omitempty" doc:"URL of the API gateway. The value can be the complete URL or an alias. The valid aliases are 'production', 'staging' and 'integration'."`
+	URL          string   `json:"url,omitempty" doc:"URL of the API gateway. The value can be the complete URL or an alias. The valid aliases are 'production', 'staging', 'integration' and the aliases in the 'aliases.json' file."`
 	Insecure     bool     `json:"insecure,omitempty" doc:"Enables insecure communication with the server. This disables verification of TLS certificates and host names."`
+
+	CredentialStore string `json:"credential_store,omitempty" doc:"The credential store that the client secret and tokens are saved to, the valid values are 'keyring', 'encrypted-file' and 'file'."`
+
+	// credentialsErr is the reason why the credentials cannot be loaded from the credential store,
+	// the commands that don't call the API gateway can still run without the credentials.
+	credentialsErr error
 }
 
-// Save saves the given configuration to the configuration file.
+// Save saves the given configuration to the configuration file of the active profile, the client
+// secret and tokens are saved to the credential store.
 func (c *APIConfig) Save() error {
-	dir, err := ConfigDir()
+	file, err := apiConfigFile()
+	if err != nil {
+		return err
+	}
+
+	key, err := profileCredentialKey(ActiveProfile())
 	if err != nil {
 		return err
 	}
-	file := filepath.Join(dir, "xcm.json")
-	data, err := json.MarshalIndent(c, "", "  ")
+
+	credentials := &apiCredentials{
+		ClientSecret: c.ClientSecret,
+		AccessToken:  c.AccessToken,
+		RefreshToken: c.RefreshToken,
+	}
+	if credentials.empty() {
+		if err := deleteCredential(c.CredentialStore, key); err != nil {
+			return err
+		}
+		c.CredentialStore = ""
+	} else {
+		credentialsData, err := marshalCredentials(credentials)
+		if err != nil {
+			return err
+		}
+		backend, err := saveCredential(c.CredentialStore, key, credentialsData)
+		if err != nil {
+			return fmt.Errorf("canot save credentials: %v", err)
+		}
+		c.CredentialStore = backend
+	}
+
+	saved := *c
+	saved.ClientSecret = ""
+	saved.AccessToken = ""
+	saved.RefreshToken = ""
+	data, err := json.MarshalIndent(saved, "", "  ")
 	if err != nil {
 		return fmt.Errorf("canot marshal config: %v", err)
 	}
@@ -44,11 +86,19 @@ func (c *APIConfig) Save() error {
 // Armed checks if the configuration contains either credentials or tokens that haven't expired, so
 // that it can be used to perform authenticated requests.
 func (c *APIConfig) Armed() (armed bool, reason string, err error) {
+	if c.credentialsErr != nil {
+		reason = c.credentialsErr.Error()
+		return
+	}
+
 	// Check URLs:
 	haveURL := c.URL != ""
 	haveTokenURL := c.TokenURL != ""
 	haveURLs := haveURL && haveTokenURL
 
+	// Check credentials:
+	haveCredentials := c.ClientID != "" && c.ClientSecret != ""
+
 	// Check tokens:
 	haveAccess := c.AccessToken != ""
 	accessUsable := false
@@ -74,7 +124,7 @@ func (c *APIConfig) Armed() (armed bool, reason string, err error) {
 	}
 
 	// Calculate the result:
-	armed = haveURLs && (accessUsable || refreshUsable)
+	armed = haveURLs && (haveCredentials || accessUsable || refreshUsable)
 	if armed {
 		return
 	}
@@ -84,6 +134,8 @@ func (c *APIConfig) Armed() (armed bool, reason string, err error) {
 	// credentials is more important than missing URLs, so that condition should be checked
 	// first.
 	switch {
+	case !haveCredentials && !haveAccess && !haveRefresh:
+		reason = "credentials aren't set"
 	case haveAccess && !haveRefresh && !accessUsable:
 		reason = "access token is expired"
 	case !haveAccess && haveRefresh && !refreshUsable:
@@ -101,8 +153,19 @@ func (c *APIConfig) Armed() (armed bool, reason string, err error) {
 	return
 }
 
+// AccessTokenUsable checks if the configuration contains an access token that hasn't expired.
+func (c *APIConfig) AccessTokenUsable() (bool, error) {
+	if c.AccessToken == "" {
+		return false, nil
+	}
+
+	return tokenUsable(c.AccessToken, 5*time.Second)
+}
+
 // Disarm removes from the configuration all the settings that are needed for authentication.
 func (c *APIConfig) Disarm() {
+	c.ClientID = ""
+	c.ClientSecret = ""
 	c.AccessToken = ""
 	c.RefreshToken = ""
 	c.Scopes = nil
@@ -110,6 +173,12 @@ func (c *APIConfig) Disarm() {
 	c.URL = ""
 }
 
+// GatewayURL returns the URL of the API gateway, the alias is resolved to its URL.
+func (c *APIConfig) GatewayURL() (string, error) {
+	url, _, err := ResolveURL(c.URL)
+	return url, err
+}
+
 // Connection creates a connection using this configuration.
 func (c *APIConfig) Connection() (connection *sdk.Connection, err error) {
 	// Create the logger:
@@ -136,11 +205,18 @@ func (c *APIConfig) Connection() (connection *sdk.Connection, err error) {
 	if c.TokenURL != "" {
 		builder.TokenURL(c.TokenURL)
 	}
+	if c.ClientID != "" || c.ClientSecret != "" {
+		builder.Client(c.ClientID, c.ClientSecret)
+	}
 	if c.Scopes != nil {
 		builder.Scopes(c.Scopes...)
 	}
 	if c.URL != "" {
-		builder.URL(c.URL)
+		url, err := c.GatewayURL()
+		if err != nil {
+			return nil, err
+		}
+		builder.URL(url)
 	}
 	tokens := make([]string, 0, 2)
 	if c.AccessToken != "" {
@@ -180,16 +256,16 @@ func ConfigDir() (dir string, err error) {
 	return dir, nil
 }
 
-// LoadAPIConfig loads the configuration from the configuration file. If the configuration file doesn't exist
-// it will return an empty configuration object.
+// LoadAPIConfig loads the configuration from the configuration file of the active profile and the
+// credential store. If the configuration file doesn't exist it will return an empty configuration
+// object. The plaintext client secret and tokens of the previous versions are moved to the
+// credential store.
 func LoadAPIConfig() (*APIConfig, error) {
-	dir, err := ConfigDir()
+	file, err := apiConfigFile()
 	if err != nil {
 		return nil, err
 	}
 
-	file := filepath.Join(dir, "xcm.json")
-
 	_, err = os.Stat(file)
 	if os.IsNotExist(err) {
 		return &APIConfig{}, nil
@@ -212,5 +288,40 @@ func LoadAPIConfig() (*APIConfig, error) {
 		return nil, fmt.Errorf("can't parse config file '%s': %v", file, err)
 	}
 
+	if cfg.ClientSecret != "" || cfg.AccessToken != "" || cfg.RefreshToken != "" {
+		if err := cfg.Save(); err != nil {
+			return nil, fmt.Errorf("canot migrate the credentials of config file '%s': %v", file, err)
+		}
+		return cfg, nil
+	}
+
+	if cfg.CredentialStore == "" {
+		return cfg, nil
+	}
+
+	key, err := profileCredentialKey(ActiveProfile())
+	if err != nil {
+		return nil, err
+	}
+	credentialsData, err := loadCredential(cfg.CredentialStore, key)
+	if errors.Is(err, credstore.ErrPassphraseRequired) {
+		cfg.credentialsErr = err
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("canot load credentials: %v", err)
+	}
+	if credentialsData == nil {
+		return cfg, nil
+	}
+
+	credentials := &apiCredentials{}
+	if err := json.Unmarshal(credentialsData, credentials); err != nil {
+		return nil, fmt.Errorf("can't parse credentials: %v", err)
+	}
+	cfg.ClientSecret = credentials.ClientSecret
+	cfg.AccessToken = credentials.AccessToken
+	cfg.RefreshToken = credentials.RefreshToken
+
 	return cfg, nil
 }
@@ -0,0 +1,40 @@
+package configs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeeey/xcm-cli/pkg/credstore"
+)
+
+func TestLoadAPIConfigWithoutPassphrase(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(credstore.BackendEnv, credstore.BackendEncryptedFile)
+	t.Setenv(credstore.PassphraseEnv, "passphrase")
+
+	if err := (&APIConfig{
+		URL:          "https://api.openshift.com",
+		TokenURL:     "https://sso.redhat.com/token",
+		RefreshToken: "token",
+	}).Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the commands that don't call the API gateway run without the passphrase, e.g. connect --render
+	t.Setenv(credstore.PassphraseEnv, "")
+	cfg, err := LoadAPIConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.URL != "https://api.openshift.com" || cfg.RefreshToken != "" {
+		t.Errorf("unexpected config %v", cfg)
+	}
+
+	armed, reason, err := cfg.Armed()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if armed || !strings.Contains(reason, credstore.PassphraseEnv) {
+		t.Errorf("expected the passphrase is required, but got %t, %q", armed, reason)
+	}
+}
@@ -1,14 +1,77 @@
 package configs
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/skeeey/xcm-cli/pkg/cert"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+const (
+	controlPlaneContextsFileName = "controlplanes.json"
+	controlPlaneKubeConfigDir    = "controlplanes"
+
+	// legacyControlPlaneID is the ID of the context that is migrated from the legacy control plane
+	// kubeconfig.
+	legacyControlPlaneID = "default"
+)
+
+// ControlPlaneContext is a control plane that is managed on this workstation.
+type ControlPlaneContext struct {
+	ID     string `json:"id"`
+	Server string `json:"server,omitempty"`
+
+	// CredentialStore is the credential store that the kubeconfig is saved to, it's empty if the
+	// kubeconfig is saved to a file by the previous versions.
+	CredentialStore string `json:"credential_store,omitempty"`
+
+	// HostingKubeconfig is the kubeconfig file of the cluster that the connector is deployed on.
+	HostingKubeconfig string `json:"hosting_kubeconfig,omitempty"`
+
+	// Provider is the provider of the cluster that the connector is deployed on, it's empty if the
+	// cluster was connected by the previous versions.
+	Provider string `json:"provider,omitempty"`
+
+	// Expose and ExternalHostname are how the control plane is exposed, they're empty if the control
+	// plane is exposed with a load balancer or was connected by the previous versions.
+	Expose           string `json:"expose,omitempty"`
+	ExternalHostname string `json:"external_hostname,omitempty"`
+
+	// Images are the images that the connector was deployed with, they're reused when the connector
+	// is upgraded.
+	Images *Images `json:"images,omitempty"`
+
+	// RelayedClusters are the kubeconfig files of the clusters that are relayed to the control plane,
+	// the key is the cluster id.
+	RelayedClusters map[string]string `json:"relayed_clusters,omitempty"`
+
+	// RelayedClusterImages are the images that the agents of the relayed clusters were deployed with,
+	// the key is the cluster id.
+	RelayedClusterImages map[string]*Images `json:"relayed_cluster_images,omitempty"`
+}
+
+// Images are the images and the image registry that were given explicitly to deploy the components,
+// the default images of xcm are used for the empty ones.
+type Images struct {
+	ControlPlane string `json:"controlplane,omitempty"`
+	Connector    string `json:"connector,omitempty"`
+	Agent        string `json:"agent,omitempty"`
+	Registry     string `json:"registry,omitempty"`
+}
+
+// ControlPlaneContexts are the control plane contexts in the configuration directory, the
+// kubeconfig of each control plane is saved to the credential store.
+type ControlPlaneContexts struct {
+	CurrentContext string                          `json:"current_context,omitempty"`
+	Contexts       map[string]*ControlPlaneContext `json:"contexts,omitempty"`
+}
+
 func BuildControlPlaneKubeConfig(host string, certs *cert.APIServerCerts) clientcmdapi.Config {
 	config := clientcmdapi.Config{
 		Clusters: map[string]*clientcmdapi.Cluster{"default-cluster": {
@@ -30,13 +93,446 @@ func BuildControlPlaneKubeConfig(host string, certs *cert.APIServerCerts) client
 	return config
 }
 
-func SaveControlPlaneKubeConfig(kubeconfig []byte) error {
-	configDir, err := ConfigDir()
+// SaveControlPlaneKubeConfig saves the kubeconfig of the given control plane to the credential store
+// and makes the control plane as the current context.
+func SaveControlPlaneKubeConfig(id string, kubeconfig []byte) error {
+	return saveControlPlaneKubeConfig(id, kubeconfig, true)
+}
+
+// UpdateControlPlaneKubeConfig saves the kubeconfig of the given control plane to the credential
+// store, e.g. after its certificates are rotated, the current context is not changed.
+func UpdateControlPlaneKubeConfig(id string, kubeconfig []byte) error {
+	return saveControlPlaneKubeConfig(id, kubeconfig, false)
+}
+
+func saveControlPlaneKubeConfig(id string, kubeconfig []byte, current bool) error {
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		return err
+	}
+
+	fileName, err := controlPlaneKubeConfigFileName(id)
+	if err != nil {
+		return err
+	}
+
+	recorded := ""
+	if context, ok := contexts.Contexts[id]; ok {
+		recorded = context.CredentialStore
+	}
+
+	backend, err := saveCredential(recorded, controlPlaneCredentialKey(id), kubeconfig)
+	if err != nil {
+		return fmt.Errorf("canot save the kubeconfig of control plane %q: %v", id, err)
+	}
+
+	if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	server := ""
+	if config, err := clientcmd.Load(kubeconfig); err == nil {
+		if context, ok := config.Contexts[config.CurrentContext]; ok {
+			if cluster, ok := config.Clusters[context.Cluster]; ok {
+				server = cluster.Server
+			}
+		}
+	}
+
+	context, ok := contexts.Contexts[id]
+	if !ok {
+		context = &ControlPlaneContext{ID: id}
+		contexts.Contexts[id] = context
+	}
+	context.Server = server
+	context.CredentialStore = backend
+	if current || contexts.CurrentContext == "" {
+		contexts.CurrentContext = id
+	}
+	return contexts.Save()
+}
+
+// ControlPlaneKubeConfig returns the kubeconfig of the given control plane, if the id is empty, the
+// current context is used. The kubeconfig file of the previous versions is moved to the credential
+// store.
+func ControlPlaneKubeConfig(id string) ([]byte, error) {
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		return nil, err
+	}
+
+	context, err := contexts.get(id)
+	if err != nil {
+		return nil, err
+	}
+	id = context.ID
+
+	fileName, err := controlPlaneKubeConfigFileName(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if context.CredentialStore == "" {
+		kubeconfig, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("canot read the kubeconfig of control plane %q: %v", id, err)
+		}
+
+		backend, err := saveCredential("", controlPlaneCredentialKey(id), kubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("canot migrate the kubeconfig of control plane %q: %v", id, err)
+		}
+		if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		context.CredentialStore = backend
+		return kubeconfig, contexts.Save()
+	}
+
+	kubeconfig, err := loadCredential(context.CredentialStore, controlPlaneCredentialKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("canot load the kubeconfig of control plane %q: %v", id, err)
+	}
+	if kubeconfig == nil {
+		return nil, fmt.Errorf("the kubeconfig of control plane %q is not found in the %s credential store",
+			id, context.CredentialStore)
+	}
+
+	return kubeconfig, nil
+}
+
+// GetControlPlaneContext returns the given control plane context, if the id is empty, the current
+// context is returned.
+func GetControlPlaneContext(id string) (*ControlPlaneContext, error) {
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		return nil, err
+	}
+
+	return contexts.get(id)
+}
+
+// RecordHostingCluster records the kubeconfig file and the provider of the cluster that the connector
+// of the given control plane is deployed on, so that the connector can be upgraded later.
+func RecordHostingCluster(id, kubeconfigPath, provider string) error {
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		return err
+	}
+
+	context, err := contexts.get(id)
+	if err != nil {
+		return err
+	}
+
+	path, err := absPath(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	context.HostingKubeconfig = path
+	context.Provider = provider
+	return contexts.Save()
+}
+
+// RecordExposure records how the given control plane is exposed, so that the exposure can be checked
+// and removed later.
+func RecordExposure(id, expose, externalHostname string) error {
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		return err
+	}
+
+	context, err := contexts.get(id)
+	if err != nil {
+		return err
+	}
+
+	context.Expose = expose
+	context.ExternalHostname = externalHostname
+	return contexts.Save()
+}
+
+// RecordImages records the images that the connector of the given control plane was deployed with.
+func RecordImages(id string, images *Images) error {
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		return err
+	}
+
+	context, err := contexts.get(id)
+	if err != nil {
+		return err
+	}
+
+	context.Images = images
+	return contexts.Save()
+}
+
+// HostingClusterContext returns the control plane context whose connector is deployed on the cluster
+// of the given kubeconfig file, nil is returned if the cluster is not recorded.
+func HostingClusterContext(kubeconfigPath string) (*ControlPlaneContext, error) {
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := absPath(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, context := range contexts.List() {
+		if context.HostingKubeconfig == path {
+			return &context, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// RecordRelayedCluster records the kubeconfig file of a cluster that is relayed to the given control
+// plane and the images that its agent was deployed with, if the control plane id is empty, the current
+// context is used.
+func RecordRelayedCluster(controlPlaneID, clusterID, kubeconfigPath string, images *Images) error {
+	contexts, err := LoadControlPlaneContexts()
 	if err != nil {
 		return err
 	}
 
-	fileName := filepath.Join(configDir, "controlplane-admin.kubeconfig")
-	// TODO read the file and compare content
-	return os.WriteFile(fileName, kubeconfig, 0600)
+	context, err := contexts.get(controlPlaneID)
+	if err != nil {
+		return err
+	}
+
+	path, err := absPath(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	if context.RelayedClusters == nil {
+		context.RelayedClusters = map[string]string{}
+	}
+	context.RelayedClusters[clusterID] = path
+	delete(context.RelayedClusterImages, clusterID)
+	if images != nil {
+		if context.RelayedClusterImages == nil {
+			context.RelayedClusterImages = map[string]*Images{}
+		}
+		context.RelayedClusterImages[clusterID] = images
+	}
+	return contexts.Save()
+}
+
+// ForgetRelayedCluster removes a relayed cluster from the given control plane context, if the control
+// plane id is empty, the current context is used. The context or the cluster that is not found is
+// ignored.
+func ForgetRelayedCluster(controlPlaneID, clusterID string) error {
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		return err
+	}
+
+	context, err := contexts.get(controlPlaneID)
+	if err != nil {
+		return nil
+	}
+
+	if _, ok := context.RelayedClusters[clusterID]; !ok {
+		return nil
+	}
+
+	delete(context.RelayedClusters, clusterID)
+	delete(context.RelayedClusterImages, clusterID)
+	return contexts.Save()
+}
+
+// UseControlPlaneContext makes the given control plane as the current context.
+func UseControlPlaneContext(id string) error {
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := contexts.Contexts[id]; !ok {
+		return fmt.Errorf("the control plane context %q is not found", id)
+	}
+
+	contexts.CurrentContext = id
+	return contexts.Save()
+}
+
+// DeleteControlPlaneContext deletes the given control plane context and its kubeconfig, the context
+// that is not found is ignored.
+func DeleteControlPlaneContext(id string) error {
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		return err
+	}
+
+	fileName, err := controlPlaneKubeConfigFileName(id)
+	if err != nil {
+		return err
+	}
+
+	if context, ok := contexts.Contexts[id]; ok {
+		if err := deleteCredential(context.CredentialStore, controlPlaneCredentialKey(id)); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	delete(contexts.Contexts, id)
+	if contexts.CurrentContext == id {
+		contexts.CurrentContext = ""
+	}
+
+	return contexts.Save()
+}
+
+// get returns the given control plane context, if the id is empty, the current context is returned.
+func (c *ControlPlaneContexts) get(id string) (*ControlPlaneContext, error) {
+	if id == "" {
+		id = c.CurrentContext
+	}
+
+	if id == "" {
+		return nil, fmt.Errorf("there is no current control plane context, connect a cluster or use a context")
+	}
+
+	context, ok := c.Contexts[id]
+	if !ok {
+		return nil, fmt.Errorf("the control plane context %q is not found", id)
+	}
+
+	return context, nil
+}
+
+// List returns the control plane contexts sorted by the id.
+func (c *ControlPlaneContexts) List() []ControlPlaneContext {
+	ids := []string{}
+	for id := range c.Contexts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	contexts := []ControlPlaneContext{}
+	for _, id := range ids {
+		contexts = append(contexts, *c.Contexts[id])
+	}
+
+	return contexts
+}
+
+// Save saves the control plane contexts to the configuration directory.
+func (c *ControlPlaneContexts) Save() error {
+	dir, err := ConfigDir()
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Join(dir, controlPlaneContextsFileName)
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("canot marshal control plane contexts: %v", err)
+	}
+
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return fmt.Errorf("canot write file '%s': %v", file, err)
+	}
+
+	return nil
+}
+
+// LoadControlPlaneContexts loads the control plane contexts from the configuration directory. The
+// legacy control plane kubeconfig is migrated to a context with the id 'default'.
+func LoadControlPlaneContexts() (*ControlPlaneContexts, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	contexts := &ControlPlaneContexts{}
+	file := filepath.Join(dir, controlPlaneContextsFileName)
+	data, err := os.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("canot read file '%s': %v", file, err)
+	case len(data) > 0:
+		if err := json.Unmarshal(data, contexts); err != nil {
+			return nil, fmt.Errorf("can't parse file '%s': %v", file, err)
+		}
+	}
+
+	if contexts.Contexts == nil {
+		contexts.Contexts = map[string]*ControlPlaneContext{}
+	}
+
+	if err := migrateLegacyControlPlaneKubeConfig(dir, contexts); err != nil {
+		return nil, err
+	}
+
+	return contexts, nil
+}
+
+// migrateLegacyControlPlaneKubeConfig moves the legacy control plane kubeconfig to a context.
+func migrateLegacyControlPlaneKubeConfig(dir string, contexts *ControlPlaneContexts) error {
+	legacyFileName := filepath.Join(dir, constants.ControlPlaneKubeAdminFileName)
+	if _, err := os.Stat(legacyFileName); os.IsNotExist(err) {
+		return nil
+	}
+
+	if _, ok := contexts.Contexts[legacyControlPlaneID]; ok {
+		return nil
+	}
+
+	fileName, err := controlPlaneKubeConfigFileName(legacyControlPlaneID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fileName), os.FileMode(0700)); err != nil {
+		return fmt.Errorf("canot create directory %s: %v", filepath.Dir(fileName), err)
+	}
+
+	if err := os.Rename(legacyFileName, fileName); err != nil {
+		return fmt.Errorf("canot migrate file '%s': %v", legacyFileName, err)
+	}
+
+	contexts.Contexts[legacyControlPlaneID] = &ControlPlaneContext{ID: legacyControlPlaneID}
+	if contexts.CurrentContext == "" {
+		contexts.CurrentContext = legacyControlPlaneID
+	}
+
+	return contexts.Save()
+}
+
+// absPath returns the absolute path of the given file, so that the file can be found from another
+// working directory. The empty path is kept.
+func absPath(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	return filepath.Abs(path)
+}
+
+// controlPlaneKubeConfigFileName returns the kubeconfig file of the given control plane that is saved
+// by the previous versions.
+func controlPlaneKubeConfigFileName(id string) (string, error) {
+	if id == "" || filepath.Base(id) != id {
+		return "", fmt.Errorf("invalid control plane id %q", id)
+	}
+
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, controlPlaneKubeConfigDir, fmt.Sprintf("%s.kubeconfig", id)), nil
 }
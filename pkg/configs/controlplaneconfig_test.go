@@ -0,0 +1,212 @@
+package configs
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+	"github.com/skeeey/xcm-cli/pkg/credstore"
+)
+
+func newKubeConfig(t *testing.T, server string) []byte {
+	data, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"cluster": {Server: server}},
+		Contexts:       map[string]*clientcmdapi.Context{"context": {Cluster: "cluster"}},
+		CurrentContext: "context",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return data
+}
+
+func TestControlPlaneContexts(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(credstore.BackendEnv, credstore.BackendFile)
+
+	if _, err := ControlPlaneKubeConfig(""); err == nil {
+		t.Errorf("expected error when there is no current context")
+	}
+
+	if err := SaveControlPlaneKubeConfig("cp1", newKubeConfig(t, "https://cp1:443")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := SaveControlPlaneKubeConfig("cp2", newKubeConfig(t, "https://cp2:443")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contexts.CurrentContext != "cp2" {
+		t.Errorf("expected current context cp2, but got %q", contexts.CurrentContext)
+	}
+	if list := contexts.List(); len(list) != 2 || list[0].ID != "cp1" || list[0].Server != "https://cp1:443" {
+		t.Errorf("unexpected contexts %v", list)
+	}
+
+	if err := UseControlPlaneContext("cp1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubeconfig, err := ControlPlaneKubeConfig("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(kubeconfig, []byte("https://cp1:443")) {
+		t.Errorf("unexpected kubeconfig %s", kubeconfig)
+	}
+
+	// the current context is kept when the kubeconfig of another control plane is updated
+	if err := UpdateControlPlaneKubeConfig("cp2", newKubeConfig(t, "https://cp2:6443")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current, err := GetControlPlaneContext("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if current.ID != "cp1" {
+		t.Errorf("expected current context cp1, but got %q", current.ID)
+	}
+	if cp2, err := GetControlPlaneContext("cp2"); err != nil || cp2.Server != "https://cp2:6443" {
+		t.Errorf("expected the kubeconfig of cp2 is updated, but got %v, %v", cp2, err)
+	}
+
+	if err := UseControlPlaneContext("cp3"); err == nil {
+		t.Errorf("expected error when the context is not found")
+	}
+
+	if err := DeleteControlPlaneContext("cp1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store, err := credstore.New(credstore.BackendFile, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Get("controlplane.cp1"); !errors.Is(err, credstore.ErrNotFound) {
+		t.Errorf("expected the kubeconfig is deleted, but got %v", err)
+	}
+	if _, err := ControlPlaneKubeConfig(""); err == nil {
+		t.Errorf("expected error when the current context is deleted")
+	}
+	if _, err := ControlPlaneKubeConfig("cp2"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := ControlPlaneKubeConfig("../cp2"); err == nil {
+		t.Errorf("expected error with an invalid id")
+	}
+}
+
+func TestRelayedClusters(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(credstore.BackendEnv, credstore.BackendFile)
+
+	if err := RecordRelayedCluster("", "c1", "spoke.kubeconfig", nil); err == nil {
+		t.Errorf("expected error when there is no current context")
+	}
+
+	if err := SaveControlPlaneKubeConfig("cp1", newKubeConfig(t, "https://cp1:443")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RecordHostingCluster("cp1", "/tmp/hosting.kubeconfig", "kubernetes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RecordExposure("cp1", "ingress", "cp1.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RecordImages("cp1", &Images{Registry: "mirror.example.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RecordRelayedCluster("", "c1", "spoke.kubeconfig", &Images{Agent: "mirror.example.com/agent:v1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RecordRelayedCluster("cp1", "c2", "/tmp/c2.kubeconfig", &Images{Registry: "mirror.example.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the recorded clusters are kept when the kubeconfig is saved again
+	if err := SaveControlPlaneKubeConfig("cp1", newKubeConfig(t, "https://cp1:443")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ForgetRelayedCluster("", "c2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	context, err := GetControlPlaneContext("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if context.HostingKubeconfig != "/tmp/hosting.kubeconfig" || context.Provider != "kubernetes" {
+		t.Errorf("unexpected hosting cluster %q of provider %q", context.HostingKubeconfig, context.Provider)
+	}
+	if context.Expose != "ingress" || context.ExternalHostname != "cp1.example.com" {
+		t.Errorf("unexpected exposure %q with host %q", context.Expose, context.ExternalHostname)
+	}
+	if hosting, err := HostingClusterContext("/tmp/hosting.kubeconfig"); err != nil || hosting == nil || hosting.ID != "cp1" {
+		t.Errorf("expected the context of the hosting cluster, but got %v, %v", hosting, err)
+	}
+	if hosting, err := HostingClusterContext("/tmp/other.kubeconfig"); err != nil || hosting != nil {
+		t.Errorf("expected no context of an unknown cluster, but got %v, %v", hosting, err)
+	}
+	if len(context.RelayedClusters) != 1 || !filepath.IsAbs(context.RelayedClusters["c1"]) {
+		t.Errorf("unexpected relayed clusters %v", context.RelayedClusters)
+	}
+	if context.Images == nil || context.Images.Registry != "mirror.example.com" {
+		t.Errorf("unexpected images %v", context.Images)
+	}
+	if len(context.RelayedClusterImages) != 1 || context.RelayedClusterImages["c1"].Agent != "mirror.example.com/agent:v1" {
+		t.Errorf("unexpected images of the relayed clusters %v", context.RelayedClusterImages)
+	}
+
+	if err := ForgetRelayedCluster("cp2", "c1"); err != nil {
+		t.Errorf("expected the unknown context is ignored, but got %v", err)
+	}
+}
+
+func TestMigrateLegacyControlPlaneKubeConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(credstore.BackendEnv, credstore.BackendFile)
+
+	dir, err := ConfigDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	legacyFileName := filepath.Join(dir, constants.ControlPlaneKubeAdminFileName)
+	if err := os.WriteFile(legacyFileName, newKubeConfig(t, "https://legacy:443"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeconfig, err := ControlPlaneKubeConfig("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(kubeconfig, []byte("https://legacy:443")) {
+		t.Errorf("unexpected kubeconfig %s", kubeconfig)
+	}
+	if _, err := os.Stat(legacyFileName); !os.IsNotExist(err) {
+		t.Errorf("expected the legacy kubeconfig is migrated")
+	}
+
+	contexts, err := LoadControlPlaneContexts()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contexts.Contexts["default"].CredentialStore != credstore.BackendFile {
+		t.Errorf("expected the kubeconfig is moved to the credential store, but got %v", contexts.Contexts["default"])
+	}
+	if _, err := os.Stat(filepath.Join(dir, controlPlaneKubeConfigDir, "default.kubeconfig")); !os.IsNotExist(err) {
+		t.Errorf("expected the kubeconfig file is removed")
+	}
+}
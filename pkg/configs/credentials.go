@@ -0,0 +1,118 @@
+package configs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/skeeey/xcm-cli/pkg/credstore"
+)
+
+// apiCredentials are the sensitive settings of the API configuration, they are saved to the
+// credential store instead of the configuration file.
+type apiCredentials struct {
+	ClientSecret string `json:"client_secret,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+func (c *apiCredentials) empty() bool {
+	return c.ClientSecret == "" && c.AccessToken == "" && c.RefreshToken == ""
+}
+
+// credentialStore returns the credential store of the given backend, if the backend is empty, the
+// default backend is used. The returned backend is the backend that is used.
+func credentialStore(backend string) (string, credstore.Store, error) {
+	if backend == "" {
+		backend = credstore.DefaultBackend()
+	}
+
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	store, err := credstore.New(backend, dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return backend, store, nil
+}
+
+// saveCredential saves the credential to the store. The backend from the environment variable takes
+// precedence over the recorded backend, so that the credentials can be moved to another store, the
+// credential in the recorded store is deleted in that case. The backend that is used is returned.
+func saveCredential(recorded, key string, data []byte) (string, error) {
+	backend := recorded
+	if env := os.Getenv(credstore.BackendEnv); env != "" {
+		backend = env
+	}
+
+	backend, store, err := credentialStore(backend)
+	if err != nil {
+		return "", err
+	}
+
+	if err := store.Set(key, data); err != nil {
+		return "", err
+	}
+
+	if recorded != "" && recorded != backend {
+		if err := deleteCredential(recorded, key); err != nil {
+			return "", err
+		}
+	}
+
+	return backend, nil
+}
+
+// loadCredential loads the credential from the recorded store, nil is returned if the credential is
+// not found.
+func loadCredential(recorded, key string) ([]byte, error) {
+	_, store, err := credentialStore(recorded)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := store.Get(key)
+	if errors.Is(err, credstore.ErrNotFound) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// deleteCredential deletes the credential from the recorded store.
+func deleteCredential(recorded, key string) error {
+	if recorded == "" {
+		return nil
+	}
+
+	_, store, err := credentialStore(recorded)
+	if err != nil {
+		return err
+	}
+
+	return store.Delete(key)
+}
+
+func profileCredentialKey(profile string) (string, error) {
+	if profile == "" || filepath.Base(profile) != profile {
+		return "", fmt.Errorf("invalid profile %q", profile)
+	}
+	return fmt.Sprintf("profile.%s", profile), nil
+}
+
+func controlPlaneCredentialKey(id string) string {
+	return fmt.Sprintf("controlplane.%s", id)
+}
+
+func marshalCredentials(c *apiCredentials) ([]byte, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("canot marshal credentials: %v", err)
+	}
+	return data, nil
+}
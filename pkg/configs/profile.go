@@ -0,0 +1,72 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	// DefaultProfile is the profile that is used when no profile is specified, it is saved to the
+	// 'xcm.json' file.
+	DefaultProfile = "default"
+
+	// ProfileEnv is the environment variable to specify the active profile.
+	ProfileEnv = "XCM_PROFILE"
+
+	profilesDir = "profiles"
+)
+
+// AddProfileFlag adds the profile flag to the given set of command line flags.
+func AddProfileFlag(flags *pflag.FlagSet) {
+	flags.StringVar(
+		&profile,
+		"profile",
+		"",
+		fmt.Sprintf("The login profile of the xCM API gateway. The default value is taken from the '%s' "+
+			"environment variable, or '%s' if it isn't set.", ProfileEnv, DefaultProfile),
+	)
+}
+
+// ActiveProfile returns the active profile, the profile flag takes precedence over the environment
+// variable.
+func ActiveProfile() string {
+	if profile != "" {
+		return profile
+	}
+
+	if env := os.Getenv(ProfileEnv); env != "" {
+		return env
+	}
+
+	return DefaultProfile
+}
+
+// apiConfigFile returns the configuration file of the active profile.
+func apiConfigFile() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	name := ActiveProfile()
+	if name == DefaultProfile {
+		return filepath.Join(dir, "xcm.json"), nil
+	}
+
+	if filepath.Base(name) != name {
+		return "", fmt.Errorf("invalid profile %q", name)
+	}
+
+	dir = filepath.Join(dir, profilesDir)
+	if err := os.MkdirAll(dir, os.FileMode(0700)); err != nil {
+		return "", fmt.Errorf("canot create directory %s: %v", dir, err)
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%s.json", name)), nil
+}
+
+// profile is the profile that is specified by the flag.
+var profile string
@@ -0,0 +1,131 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skeeey/xcm-cli/pkg/credstore"
+)
+
+func TestProfiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(ProfileEnv, "")
+
+	if ActiveProfile() != DefaultProfile {
+		t.Errorf("expected the default profile, but got %q", ActiveProfile())
+	}
+
+	if err := (&APIConfig{URL: "https://api.openshift.com"}).Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Setenv(ProfileEnv, "staging")
+	if err := (&APIConfig{URL: "https://api.stage.openshift.com"}).Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	profile = "local"
+	defer func() { profile = "" }()
+	if ActiveProfile() != "local" {
+		t.Errorf("expected the flag takes precedence, but got %q", ActiveProfile())
+	}
+	cfg, err := LoadAPIConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.URL != "" {
+		t.Errorf("expected an empty config, but got %v", cfg)
+	}
+
+	profile = ""
+	cfg, err = LoadAPIConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.URL != "https://api.stage.openshift.com" {
+		t.Errorf("unexpected staging config %v", cfg)
+	}
+
+	cfg.Disarm()
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Setenv(ProfileEnv, DefaultProfile)
+	cfg, err = LoadAPIConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.URL != "https://api.openshift.com" {
+		t.Errorf("expected the default profile is not changed, but got %v", cfg)
+	}
+
+	profile = "../staging"
+	if _, err := LoadAPIConfig(); err == nil {
+		t.Errorf("expected error with an invalid profile")
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(ProfileEnv, "")
+	t.Setenv(credstore.BackendEnv, credstore.BackendEncryptedFile)
+	t.Setenv(credstore.PassphraseEnv, "passphrase")
+
+	dir, err := ConfigDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the config file of the previous versions has plaintext tokens
+	legacy := []byte(`{"access_token": "access", "refresh_token": "refresh", "url": "https://api.openshift.com"}`)
+	if err := os.WriteFile(filepath.Join(dir, "xcm.json"), legacy, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg, err := LoadAPIConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AccessToken != "access" || cfg.RefreshToken != "refresh" ||
+		cfg.CredentialStore != credstore.BackendEncryptedFile {
+		t.Errorf("unexpected config %v", cfg)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "xcm.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "access") || strings.Contains(string(data), "refresh") {
+		t.Errorf("expected the tokens are removed from the config file, but got %s", data)
+	}
+
+	// the recorded store is used when the environment variable isn't set
+	t.Setenv(credstore.BackendEnv, "")
+	cfg, err = LoadAPIConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AccessToken != "access" || cfg.RefreshToken != "refresh" {
+		t.Errorf("unexpected config %v", cfg)
+	}
+
+	// the credentials can be moved to another store
+	t.Setenv(credstore.BackendEnv, credstore.BackendFile)
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "credentials.enc", "profile.default")); !os.IsNotExist(err) {
+		t.Errorf("expected the credentials are removed from the previous store")
+	}
+
+	cfg.Disarm()
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "credentials", "profile.default")); !os.IsNotExist(err) {
+		t.Errorf("expected the credentials are removed after logout")
+	}
+}
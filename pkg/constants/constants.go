@@ -7,6 +7,7 @@ const ManagedClusterCRDName = "managedclusters.cluster.open-cluster-management.i
 
 const (
 	ControlPlaneName                 = "multicluster-controlplane"
+	StandbyConnectorName             = "multicluster-controlplane-connector"
 	ControlPlaneKubeconfigSecretName = "multicluster-controlplane-kubeconfig"
 	ControlPlaneKubeAdminFileName    = "controlplane-admin.kubeconfig"
 )
@@ -14,4 +15,37 @@ const (
 const (
 	DefaultControlPlaneNamespace      = "multicluster-controlplane"
 	DefaultControlPlaneAgentNamespace = "multicluster-controlplane-agent"
+
+	// DefaultBootstrapNamespace is the namespace on the control plane of the service accounts that
+	// the relayed clusters bootstrap with.
+	DefaultBootstrapNamespace = "xcm-bootstrap"
+)
+
+const (
+	ClusterIDClaimName   = "xcmid.open-cluster-management.io"
+	ProductClaimName     = "product.open-cluster-management.io"
+	PlatformClaimName    = "platform.open-cluster-management.io"
+	RegionClaimName      = "region.open-cluster-management.io"
+	KubeVersionClaimName = "kubeversion.open-cluster-management.io"
+)
+
+// ClusterUIDAnnotation is the annotation of a managed cluster on the control plane, its value is
+// the uid of the kube-system namespace of the relayed cluster, which identifies the relayed cluster.
+const ClusterUIDAnnotation = "xcm.open-cluster-management.io/cluster-uid"
+
+const (
+	BootstrapKubeconfigSecretName = "bootstrap-kubeconfig"
+	ControlPlaneAgentName         = "multicluster-controlplane-agent"
+
+	// HubKubeconfigSecretName is the secret in the namespace of the agent that has the client
+	// certificate of the agent, the agent requests the certificate from the control plane after it
+	// bootstraps.
+	HubKubeconfigSecretName = "hub-kubeconfig-secret"
+)
+
+// The default images of the deployed components, the agent runs with the control plane image.
+const (
+	DefaultControlPlaneImage = "quay.io/open-cluster-management/multicluster-controlplane"
+	DefaultConnectorImage    = "quay.io/skeeey/xcm-connector:latest"
+	DefaultAgentImage        = DefaultControlPlaneImage
 )
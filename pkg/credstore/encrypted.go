@@ -0,0 +1,183 @@
+package credstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	encryptedFileDir = "credentials.enc"
+
+	encryptedFileVersion = 1
+	saltSize             = 16
+	keySize              = 32
+
+	// the recommended scrypt parameters for interactive logins
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+)
+
+// encryptedFileStore saves each credential to a file in the directory, the file is encrypted with
+// AES-256-GCM, and the key is derived from the passphrase with scrypt and a random salt of the file.
+//
+// The format of the file is: version (1 byte) | salt (16 bytes) | nonce (12 bytes) | ciphertext
+type encryptedFileStore struct {
+	dir        string
+	passphrase func(confirm bool) ([]byte, error)
+}
+
+func (s *encryptedFileStore) Get(key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	data, err := readFile(filepath.Join(s.dir, key))
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase, err := s.passphrase(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return decrypt(passphrase, key, data)
+}
+
+func (s *encryptedFileStore) Set(key string, data []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	// the passphrase is confirmed when the store is created, otherwise it's verified with the saved
+	// credentials, so that a mistyped one doesn't encrypt the credentials with different passphrases
+	saved := s.entries()
+	passphrase, err := s.passphrase(len(saved) == 0)
+	if err != nil {
+		return err
+	}
+	if err := s.verify(passphrase, saved); err != nil {
+		return err
+	}
+
+	encrypted, err := encrypt(passphrase, key, data)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(s.dir, key, encrypted)
+}
+
+func (s *encryptedFileStore) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	return removeFile(filepath.Join(s.dir, key))
+}
+
+// entries returns the keys of the credentials that are saved in the store.
+func (s *encryptedFileStore) entries() []string {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil
+	}
+
+	keys := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && validateKey(entry.Name()) == nil {
+			keys = append(keys, entry.Name())
+		}
+	}
+	return keys
+}
+
+// verify returns an error if none of the saved credentials can be decrypted with the passphrase.
+func (s *encryptedFileStore) verify(passphrase []byte, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	for _, key := range keys {
+		data, err := readFile(filepath.Join(s.dir, key))
+		if err != nil {
+			continue
+		}
+		if _, err := decrypt(passphrase, key, data); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the passphrase doesn't match the saved credentials of the encrypted credential store")
+}
+
+// encrypt encrypts the data, the key of the credential is used as the additional data, so that the
+// encrypted files cannot be swapped.
+func encrypt(passphrase []byte, key string, data []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	encrypted := []byte{encryptedFileVersion}
+	encrypted = append(encrypted, salt...)
+	encrypted = append(encrypted, nonce...)
+	return aead.Seal(encrypted, nonce, data, []byte(key)), nil
+}
+
+func decrypt(passphrase []byte, key string, data []byte) ([]byte, error) {
+	if len(data) < 1+saltSize || data[0] != encryptedFileVersion {
+		return nil, fmt.Errorf("the credential %q is not a valid encrypted file", key)
+	}
+
+	salt := data[1 : 1+saltSize]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[1+saltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("the credential %q is not a valid encrypted file", key)
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt the credential %q, the passphrase may be wrong", key)
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,68 @@
+package credstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const plaintextFileDir = "credentials"
+
+// fileStore saves each credential to a plaintext file in the directory.
+type fileStore struct {
+	dir string
+}
+
+func (s *fileStore) Get(key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	return readFile(filepath.Join(s.dir, key))
+}
+
+func (s *fileStore) Set(key string, data []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	return writeFile(s.dir, key, data)
+}
+
+func (s *fileStore) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	return removeFile(filepath.Join(s.dir, key))
+}
+
+func readFile(fileName string) ([]byte, error) {
+	data, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("canot read file '%s': %v", fileName, err)
+	}
+	return data, nil
+}
+
+func writeFile(dir, name string, data []byte) error {
+	if err := os.MkdirAll(dir, os.FileMode(0700)); err != nil {
+		return fmt.Errorf("canot create directory %s: %v", dir, err)
+	}
+
+	fileName := filepath.Join(dir, name)
+	if err := os.WriteFile(fileName, data, 0600); err != nil {
+		return fmt.Errorf("canot write file '%s': %v", fileName, err)
+	}
+	return nil
+}
+
+func removeFile(fileName string) error {
+	if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("canot remove file '%s': %v", fileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package credstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	keyringService = "xcm-cli"
+
+	// keyringProbeKey is used to check if the keyring is available.
+	keyringProbeKey = "xcm-cli-probe"
+)
+
+// keyringStore saves each credential to the OS keyring, the credential key is the user of the
+// keyring item.
+type keyringStore struct {
+	service string
+}
+
+func (s *keyringStore) Get(key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	data, err := keyring.Get(s.service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get credential %q from the keyring: %v", key, err)
+	}
+	return []byte(data), nil
+}
+
+func (s *keyringStore) Set(key string, data []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	if err := keyring.Set(s.service, key, string(data)); err != nil {
+		return fmt.Errorf("cannot save credential %q to the keyring: %v", key, err)
+	}
+	return nil
+}
+
+func (s *keyringStore) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	err := keyring.Delete(s.service, key)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("cannot delete credential %q from the keyring: %v", key, err)
+	}
+	return nil
+}
+
+// keyringAvailable checks if the keyring can be used, e.g. there is no Secret Service on a headless
+// Linux machine.
+func keyringAvailable() bool {
+	_, err := keyring.Get(keyringService, keyringProbeKey)
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
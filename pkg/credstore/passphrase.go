@@ -0,0 +1,79 @@
+package credstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// PassphraseEnv is the environment variable to specify the passphrase of the encrypted file store.
+const PassphraseEnv = "XCM_PASSPHRASE"
+
+// ErrPassphraseRequired is returned when the passphrase of the encrypted file store is neither set
+// with the environment variable nor can be entered in a terminal.
+var ErrPassphraseRequired = errors.New("the passphrase of the encrypted credential store is required")
+
+var (
+	passphraseLock sync.Mutex
+
+	// promptedPassphrase is the passphrase that is entered by the user, it is kept in memory, so
+	// that the user is prompted once.
+	promptedPassphrase []byte
+)
+
+// Passphrase returns the passphrase of the encrypted file store from the environment variable, if
+// it isn't set, the user is prompted to enter the passphrase in the terminal. If confirm is true, the
+// passphrase is entered twice, so that a typo doesn't lock the credentials of a new store.
+func Passphrase(confirm bool) ([]byte, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	passphraseLock.Lock()
+	defer passphraseLock.Unlock()
+
+	if promptedPassphrase != nil {
+		return promptedPassphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("%w, set it with the '%s' environment variable, or choose another store with "+
+			"the '%s' environment variable", ErrPassphraseRequired, PassphraseEnv, BackendEnv)
+	}
+
+	passphrase, err := readPassphrase(fd, "Passphrase of the xcm credential store: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("the passphrase cannot be empty")
+	}
+
+	if confirm {
+		confirmed, err := readPassphrase(fd, "Confirm the passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, confirmed) {
+			return nil, fmt.Errorf("the passphrases don't match")
+		}
+	}
+
+	promptedPassphrase = passphrase
+	return promptedPassphrase, nil
+}
+
+func readPassphrase(fd int, prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the passphrase: %v", err)
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,86 @@
+package credstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	// BackendEnv is the environment variable to specify the backend of the credential store.
+	BackendEnv = "XCM_CREDENTIAL_STORE"
+
+	// BackendKeyring saves the credentials to the OS keyring, e.g. the Secret Service on Linux,
+	// the Keychain on macOS and the Credential Manager on Windows.
+	BackendKeyring = "keyring"
+
+	// BackendEncryptedFile saves the credentials to files that are encrypted with AES-GCM, the key
+	// is derived from a passphrase.
+	BackendEncryptedFile = "encrypted-file"
+
+	// BackendFile saves the credentials to plaintext files, it is only intended for tests and
+	// headless environments where the files are protected by other means.
+	BackendFile = "file"
+)
+
+// ErrNotFound is returned when the credential with the given key is not found in the store.
+var ErrNotFound = errors.New("credential not found")
+
+// Store saves the credentials of xcm, e.g. the tokens of the API gateway and the kubeconfigs of the
+// control planes, the keys are made up of letters, digits, '.', '_' and '-'.
+type Store interface {
+	// Get returns the credential with the given key, ErrNotFound is returned if it doesn't exist.
+	Get(key string) ([]byte, error)
+
+	// Set creates or updates the credential with the given key.
+	Set(key string, data []byte) error
+
+	// Delete deletes the credential with the given key, the credential that is not found is
+	// ignored.
+	Delete(key string) error
+}
+
+// Backends returns the valid backends of the credential store.
+func Backends() []string {
+	return []string{BackendKeyring, BackendEncryptedFile, BackendFile}
+}
+
+// DefaultBackend returns the backend from the environment variable, if it isn't set, the keyring is
+// used when it's available, otherwise the encrypted file is used.
+func DefaultBackend() string {
+	if backend := os.Getenv(BackendEnv); backend != "" {
+		return backend
+	}
+
+	if keyringAvailable() {
+		return BackendKeyring
+	}
+
+	return BackendEncryptedFile
+}
+
+// New returns the credential store of the given backend, the file backends save the credentials to
+// the given directory.
+func New(backend, dir string) (Store, error) {
+	switch backend {
+	case BackendKeyring:
+		return &keyringStore{service: keyringService}, nil
+	case BackendEncryptedFile:
+		return &encryptedFileStore{dir: filepath.Join(dir, encryptedFileDir), passphrase: Passphrase}, nil
+	case BackendFile:
+		return &fileStore{dir: filepath.Join(dir, plaintextFileDir)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported credential store %q, the valid values are %v", backend, Backends())
+	}
+}
+
+var keyPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+func validateKey(key string) error {
+	if !keyPattern.MatchString(key) || key == "." || key == ".." {
+		return fmt.Errorf("invalid credential key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package credstore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testStore(t *testing.T, store Store) {
+	if _, err := store.Get("profile.default"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found error, but got %v", err)
+	}
+
+	if err := store.Set("profile.default", []byte("token")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Set("profile.default", []byte("new-token")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := store.Get("profile.default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "new-token" {
+		t.Errorf("expected new-token, but got %q", data)
+	}
+
+	if err := store.Delete("profile.default"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Delete("profile.default"); err != nil {
+		t.Errorf("unexpected error when the credential is not found: %v", err)
+	}
+	if _, err := store.Get("profile.default"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found error, but got %v", err)
+	}
+
+	if err := store.Set("../profile", []byte("token")); err == nil {
+		t.Errorf("expected error with an invalid key")
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := New(BackendFile, t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testStore(t, store)
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	t.Setenv(PassphraseEnv, "passphrase")
+
+	dir := t.TempDir()
+	store, err := New(BackendEncryptedFile, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testStore(t, store)
+
+	if err := store.Set("controlplane.cp1", []byte("kubeconfig")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Set("controlplane.cp2", []byte("kubeconfig")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, encryptedFileDir, "controlplane.cp1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Contains(data, []byte("kubeconfig")) {
+		t.Errorf("expected the credential is encrypted")
+	}
+
+	// the encrypted file cannot be used as another credential
+	if err := os.WriteFile(filepath.Join(dir, encryptedFileDir, "controlplane.cp2"), data, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Get("controlplane.cp2"); err == nil {
+		t.Errorf("expected error when the encrypted files are swapped")
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := store.Get("controlplane.cp1"); err == nil {
+		t.Errorf("expected error with a wrong passphrase")
+	}
+	if err := store.Set("controlplane.cp3", []byte("kubeconfig")); err == nil {
+		t.Errorf("expected error when a credential is saved with a wrong passphrase")
+	}
+	if _, err := os.Stat(filepath.Join(dir, encryptedFileDir, "controlplane.cp3")); !os.IsNotExist(err) {
+		t.Errorf("expected the credential isn't saved with a wrong passphrase")
+	}
+}
+
+func TestEncryptedFileStoreConfirmPassphrase(t *testing.T) {
+	confirms := []bool{}
+	store := &encryptedFileStore{
+		dir: filepath.Join(t.TempDir(), encryptedFileDir),
+		passphrase: func(confirm bool) ([]byte, error) {
+			confirms = append(confirms, confirm)
+			return []byte("passphrase"), nil
+		},
+	}
+
+	if err := store.Set("profile", []byte("token")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Set("controlplane.cp1", []byte("kubeconfig")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Get("profile"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the passphrase is only confirmed when the store is created
+	if len(confirms) != 3 || !confirms[0] || confirms[1] || confirms[2] {
+		t.Errorf("unexpected passphrase confirmations %v", confirms)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("unknown", t.TempDir()); err == nil {
+		t.Errorf("expected error with an unsupported backend")
+	}
+
+	t.Setenv(BackendEnv, BackendFile)
+	if backend := DefaultBackend(); backend != BackendFile {
+		t.Errorf("expected backend %q, but got %q", BackendFile, backend)
+	}
+}
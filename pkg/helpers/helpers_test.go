@@ -3,7 +3,11 @@ package helpers
 import "testing"
 
 func TestValidateURL(t *testing.T) {
+	if err := ValidateURL("http://"); err == nil {
+		t.Errorf("expected error for the url without host")
+	}
 
-	err := ValidateURL("http://")
-	t.Errorf("unexpected error: %v", err)
+	if err := ValidateURL("https://api.openshift.com"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
 }
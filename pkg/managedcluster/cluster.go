@@ -15,24 +15,36 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 )
 
 const ManagedClusterConditionConnected string = "ManagedClusterConditionConnected"
 
-func CreateManagedCluster(ctx context.Context, clusterClient clusterclient.Interface, clusterName string) error {
+// NewManagedCluster returns a managed cluster that is accepted by the hub.
+func NewManagedCluster(clusterName string, annotations map[string]string) *clusterv1.ManagedCluster {
+	return &clusterv1.ManagedCluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clusterv1.SchemeGroupVersion.String(),
+			Kind:       "ManagedCluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        clusterName,
+			Annotations: annotations,
+		},
+		Spec: clusterv1.ManagedClusterSpec{
+			HubAcceptsClient: true,
+		},
+	}
+}
+
+func CreateManagedCluster(ctx context.Context, clusterClient clusterclient.Interface,
+	clusterName string, annotations map[string]string) error {
 	return wait.Poll(10*time.Second, genericflags.TimeOut(), func() (bool, error) {
 		_, err := clusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
 		if errors.IsNotFound(err) {
 			if _, err := clusterClient.ClusterV1().ManagedClusters().Create(
 				ctx,
-				&clusterv1.ManagedCluster{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: clusterName,
-					},
-					Spec: clusterv1.ManagedClusterSpec{
-						HubAcceptsClient: true,
-					},
-				},
+				NewManagedCluster(clusterName, annotations),
 				metav1.CreateOptions{},
 			); err != nil {
 				return false, nil
@@ -75,22 +87,90 @@ func CreateClusterClaim(ctx context.Context, clusterClient clusterclient.Interfa
 
 }
 
-func WaitManagedClusterConnected(ctx context.Context, clusterClient clusterclient.Interface, clusterName string) error {
-	return wait.Poll(1*time.Second, genericflags.TimeOut(), func() (bool, error) {
-		cluster, err := clusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+// ApplyClusterClaim creates the cluster claim, or updates its value if the claim already exists. The
+// apply is retried on conflicts, the other errors are returned.
+func ApplyClusterClaim(ctx context.Context, clusterClient clusterclient.Interface, claim *clusterv1alpha1.ClusterClaim) error {
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return errors.IsConflict(err) || errors.IsAlreadyExists(err)
+	}, func() error {
+		found, err := clusterClient.ClusterV1alpha1().ClusterClaims().Get(ctx, claim.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err := clusterClient.ClusterV1alpha1().ClusterClaims().Create(ctx, claim, metav1.CreateOptions{})
+			return err
+		}
 		if err != nil {
-			return false, err
+			return err
+		}
+
+		if found.Spec.Value == claim.Spec.Value {
+			return nil
 		}
 
-		if meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable) &&
-			meta.IsStatusConditionTrue(cluster.Status.Conditions, ManagedClusterConditionConnected) {
+		found = found.DeepCopy()
+		found.Spec.Value = claim.Spec.Value
+		_, err = clusterClient.ClusterV1alpha1().ClusterClaims().Update(ctx, found, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// GetClusterClaim returns the value of the given cluster claim, if the claim is not found, an empty
+// value is returned.
+func GetClusterClaim(ctx context.Context, clusterClient clusterclient.Interface, name string) (string, error) {
+	claim, err := clusterClient.ClusterV1alpha1().ClusterClaims().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return claim.Spec.Value, nil
+}
+
+// DeleteClusterClaims deletes the given cluster claims, the claims that are not found are ignored.
+func DeleteClusterClaims(ctx context.Context, clusterClient clusterclient.Interface, names ...string) error {
+	for _, name := range names {
+		err := clusterClient.ClusterV1alpha1().ClusterClaims().Delete(ctx, name, metav1.DeleteOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteManagedCluster deletes the managed cluster from the hub and waits until it is removed.
+func DeleteManagedCluster(ctx context.Context, clusterClient clusterclient.Interface, clusterName string) error {
+	err := clusterClient.ClusterV1().ManagedClusters().Delete(ctx, clusterName, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return wait.PollImmediate(1*time.Second, genericflags.TimeOut(), func() (bool, error) {
+		_, err := clusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
 			return true, nil
 		}
+		if err != nil {
+			return false, err
+		}
 
 		return false, nil
 	})
 }
 
+// IsManagedClusterConnected returns true if the managed cluster is available and connected.
+func IsManagedClusterConnected(cluster *clusterv1.ManagedCluster) bool {
+	return meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable) &&
+		meta.IsStatusConditionTrue(cluster.Status.Conditions, ManagedClusterConditionConnected)
+}
+
 func GetClusterName(id string) string {
 	return fmt.Sprintf("cluster-%s", id)
 }
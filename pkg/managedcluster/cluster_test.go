@@ -0,0 +1,54 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clienttesting "k8s.io/client-go/testing"
+	fakecluster "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+)
+
+func newClaim(value string) *clusterv1alpha1.ClusterClaim {
+	return &clusterv1alpha1.ClusterClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "region.open-cluster-management.io"},
+		Spec:       clusterv1alpha1.ClusterClaimSpec{Value: value},
+	}
+}
+
+func TestApplyClusterClaim(t *testing.T) {
+	clusterClient := fakecluster.NewSimpleClientset()
+	if err := ApplyClusterClaim(context.TODO(), clusterClient, newClaim("us-east-1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the first update conflicts and is retried
+	conflicts := 1
+	clusterClient.PrependReactor("update", "clusterclaims", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if conflicts == 0 {
+			return false, nil, nil
+		}
+		conflicts--
+		return true, nil, errors.NewConflict(schema.GroupResource{Resource: "clusterclaims"}, "region", fmt.Errorf("conflict"))
+	})
+	if err := ApplyClusterClaim(context.TODO(), clusterClient, newClaim("us-east-2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, err := GetClusterClaim(context.TODO(), clusterClient, "region.open-cluster-management.io"); err != nil ||
+		value != "us-east-2" {
+		t.Errorf("expected the claim is updated, but got %q, %v", value, err)
+	}
+
+	// the other errors are returned
+	clusterClient.PrependReactor("get", "clusterclaims", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewForbidden(schema.GroupResource{Resource: "clusterclaims"}, "region", fmt.Errorf("forbidden"))
+	})
+	if err := ApplyClusterClaim(context.TODO(), clusterClient, newClaim("us-east-3")); !errors.IsForbidden(err) {
+		t.Errorf("expected the forbidden error, but got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package managedcluster
+
+import (
+	"context"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+const (
+	ProductOpenShift = "OpenShift"
+	ProductEKS       = "EKS"
+	ProductGKE       = "GKE"
+	ProductAKS       = "AKS"
+	ProductIKS       = "IKS"
+	ProductOther     = "Other"
+)
+
+const (
+	PlatformAWS       = "AWS"
+	PlatformGCP       = "GCP"
+	PlatformAzure     = "Azure"
+	PlatformIBM       = "IBM"
+	PlatformOpenStack = "OpenStack"
+	PlatformVSphere   = "VSphere"
+	PlatformOther     = "Other"
+)
+
+const (
+	regionLabel           = "topology.kubernetes.io/region"
+	deprecatedRegionLabel = "failure-domain.beta.kubernetes.io/region"
+	aksClusterLabel       = "kubernetes.azure.com/cluster"
+	openshiftAPIGroup     = "config.openshift.io"
+)
+
+// the prefixes of the node provider ID, e.g. aws:///us-east-2a/i-0123456789abcdef0
+var platformProviderIDPrefixes = map[string]string{
+	"aws://":       PlatformAWS,
+	"gce://":       PlatformGCP,
+	"azure://":     PlatformAzure,
+	"ibm://":       PlatformIBM,
+	"openstack://": PlatformOpenStack,
+	"vsphere://":   PlatformVSphere,
+}
+
+// DetectClusterClaims detects the product, platform, region and kube version of a cluster from its
+// nodes, server version and API groups. The key of the returned claims is the claim name, the claims
+// that cannot be detected are not returned.
+func DetectClusterClaims(ctx context.Context, kubeClient kubernetes.Interface) (map[string]string, error) {
+	claims := map[string]string{}
+
+	serverVersion, err := kubeClient.Discovery().ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	if serverVersion.GitVersion != "" {
+		claims[constants.KubeVersionClaimName] = serverVersion.GitVersion
+	}
+
+	groups, err := kubeClient.Discovery().ServerGroups()
+	if err != nil {
+		return nil, err
+	}
+	isOpenShift := false
+	for _, group := range groups.Groups {
+		if group.Name == openshiftAPIGroup {
+			isOpenShift = true
+			break
+		}
+	}
+
+	nodes, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	platform, region, isAKS := "", "", false
+	for _, node := range nodes.Items {
+		if platform == "" {
+			platform = detectPlatform(node.Spec.ProviderID)
+		}
+		if region == "" {
+			region = node.Labels[regionLabel]
+		}
+		if region == "" {
+			region = node.Labels[deprecatedRegionLabel]
+		}
+		if _, ok := node.Labels[aksClusterLabel]; ok {
+			isAKS = true
+		}
+	}
+
+	if platform == "" {
+		platform = PlatformOther
+	}
+	claims[constants.PlatformClaimName] = platform
+
+	if region != "" {
+		claims[constants.RegionClaimName] = region
+	}
+
+	claims[constants.ProductClaimName] = detectProduct(isOpenShift, isAKS, platform, serverVersion.GitVersion)
+
+	return claims, nil
+}
+
+func detectPlatform(providerID string) string {
+	for prefix, platform := range platformProviderIDPrefixes {
+		if strings.HasPrefix(providerID, prefix) {
+			return platform
+		}
+	}
+
+	return ""
+}
+
+func detectProduct(isOpenShift, isAKS bool, platform, gitVersion string) string {
+	switch {
+	case isOpenShift:
+		return ProductOpenShift
+	case isAKS:
+		return ProductAKS
+	case platform == PlatformAWS && strings.Contains(gitVersion, "-eks-"):
+		return ProductEKS
+	case platform == PlatformGCP && strings.Contains(gitVersion, "-gke."):
+		return ProductGKE
+	case platform == PlatformIBM && strings.Contains(gitVersion, "IKS"):
+		return ProductIKS
+	}
+
+	return ProductOther
+}
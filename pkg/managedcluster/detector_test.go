@@ -0,0 +1,128 @@
+package managedcluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+func newNode(name, providerID string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: providerID,
+		},
+	}
+}
+
+func TestDetectClusterClaims(t *testing.T) {
+	cases := []struct {
+		name       string
+		gitVersion string
+		apiGroups  []string
+		nodes      []runtime.Object
+		expected   map[string]string
+	}{
+		{
+			name:       "eks",
+			gitVersion: "v1.23.7-eks-4721010",
+			nodes: []runtime.Object{
+				newNode("node1", "aws:///us-east-2a/i-0123456789abcdef0",
+					map[string]string{regionLabel: "us-east-2"}),
+			},
+			expected: map[string]string{
+				constants.ProductClaimName:     ProductEKS,
+				constants.PlatformClaimName:    PlatformAWS,
+				constants.RegionClaimName:      "us-east-2",
+				constants.KubeVersionClaimName: "v1.23.7-eks-4721010",
+			},
+		},
+		{
+			name:       "gke with deprecated region label",
+			gitVersion: "v1.22.8-gke.202",
+			nodes: []runtime.Object{
+				newNode("node1", "gce://project/us-central1-c/node1",
+					map[string]string{deprecatedRegionLabel: "us-central1"}),
+			},
+			expected: map[string]string{
+				constants.ProductClaimName:     ProductGKE,
+				constants.PlatformClaimName:    PlatformGCP,
+				constants.RegionClaimName:      "us-central1",
+				constants.KubeVersionClaimName: "v1.22.8-gke.202",
+			},
+		},
+		{
+			name:       "aks",
+			gitVersion: "v1.23.5",
+			nodes: []runtime.Object{
+				newNode("node1", "azure:///subscriptions/s/resourceGroups/rg/providers/vm/node1",
+					map[string]string{regionLabel: "eastus", aksClusterLabel: "rg"}),
+			},
+			expected: map[string]string{
+				constants.ProductClaimName:     ProductAKS,
+				constants.PlatformClaimName:    PlatformAzure,
+				constants.RegionClaimName:      "eastus",
+				constants.KubeVersionClaimName: "v1.23.5",
+			},
+		},
+		{
+			name:       "openshift",
+			gitVersion: "v1.23.5+3afdacb",
+			apiGroups:  []string{"config.openshift.io/v1"},
+			nodes: []runtime.Object{
+				newNode("node1", "aws:///us-west-1a/i-0123456789abcdef0",
+					map[string]string{regionLabel: "us-west-1"}),
+			},
+			expected: map[string]string{
+				constants.ProductClaimName:     ProductOpenShift,
+				constants.PlatformClaimName:    PlatformAWS,
+				constants.RegionClaimName:      "us-west-1",
+				constants.KubeVersionClaimName: "v1.23.5+3afdacb",
+			},
+		},
+		{
+			name:       "kind",
+			gitVersion: "v1.24.0",
+			nodes: []runtime.Object{
+				newNode("node1", "kind://docker/kind/kind-control-plane", map[string]string{}),
+			},
+			expected: map[string]string{
+				constants.ProductClaimName:     ProductOther,
+				constants.PlatformClaimName:    PlatformOther,
+				constants.KubeVersionClaimName: "v1.24.0",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(c.nodes...)
+			discovery := kubeClient.Discovery().(*fakediscovery.FakeDiscovery)
+			discovery.FakedServerVersion = &version.Info{GitVersion: c.gitVersion}
+			for _, groupVersion := range c.apiGroups {
+				discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{GroupVersion: groupVersion})
+			}
+
+			claims, err := DetectClusterClaims(context.TODO(), kubeClient)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(claims, c.expected) {
+				t.Errorf("expected %v, but got %v", c.expected, claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+
+	defaultPollInterval = 5 * time.Second
+)
+
+// Token is the response of the token endpoint.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+}
+
+// DeviceAuthorization is the response of the device authorization endpoint.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+// errorResponse is the error response of the token and device authorization endpoints.
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func (e *errorResponse) String() string {
+	if e.ErrorDescription == "" {
+		return e.Error
+	}
+	return fmt.Sprintf("%s: %s", e.Error, e.ErrorDescription)
+}
+
+// DeviceAuthorizationURL returns the device authorization endpoint of an OpenID provider from its
+// token endpoint, e.g. the device authorization endpoint of
+// https://sso.redhat.com/auth/realms/redhat-external/protocol/openid-connect/token is
+// https://sso.redhat.com/auth/realms/redhat-external/protocol/openid-connect/auth/device
+func DeviceAuthorizationURL(tokenURL string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(tokenURL, "/"), "/token") + "/auth/device"
+}
+
+// ClientCredentials requests a token with the client credentials grant.
+func ClientCredentials(ctx context.Context, client *http.Client,
+	tokenURL, clientID, clientSecret string, scopes []string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", grantTypeClientCredentials)
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+
+	token := &Token{}
+	errResp, err := postForm(ctx, client, tokenURL, form, token)
+	if err != nil {
+		return nil, err
+	}
+	if errResp != nil {
+		return nil, fmt.Errorf("failed to request token with client credentials, %s", errResp)
+	}
+
+	return token, nil
+}
+
+// RequestDeviceAuthorization starts the device authorization flow, the user should open the
+// verification URI and enter the user code to authorize the device.
+func RequestDeviceAuthorization(ctx context.Context, client *http.Client,
+	deviceAuthorizationURL, clientID string, scopes []string) (*DeviceAuthorization, error) {
+	form := url.Values{}
+	form.Set("client_id", clientID)
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+
+	authorization := &DeviceAuthorization{}
+	errResp, err := postForm(ctx, client, deviceAuthorizationURL, form, authorization)
+	if err != nil {
+		return nil, err
+	}
+	if errResp != nil {
+		return nil, fmt.Errorf("failed to request device authorization, %s", errResp)
+	}
+
+	return authorization, nil
+}
+
+// PollDeviceToken polls the token endpoint until the user authorizes the device, the authorization
+// expires or the context is done.
+func PollDeviceToken(ctx context.Context, client *http.Client,
+	tokenURL, clientID string, authorization *DeviceAuthorization) (*Token, error) {
+	interval := defaultPollInterval
+	if authorization.Interval > 0 {
+		interval = time.Duration(authorization.Interval) * time.Second
+	}
+
+	if authorization.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(authorization.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", grantTypeDeviceCode)
+	form.Set("device_code", authorization.DeviceCode)
+	form.Set("client_id", clientID)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("the device authorization is expired")
+		case <-time.After(interval):
+		}
+
+		token := &Token{}
+		errResp, err := postForm(ctx, client, tokenURL, form, token)
+		if err != nil {
+			return nil, err
+		}
+		if errResp == nil {
+			return token, nil
+		}
+
+		switch errResp.Error {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, fmt.Errorf("failed to request token with device code, %s", errResp)
+		}
+	}
+}
+
+// postForm posts the form to the endpoint, the successful response is decoded to the result, and the
+// OAuth error response is returned.
+func postForm(ctx context.Context, client *http.Client,
+	endpoint string, form url.Values, result interface{}) (*errorResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return nil, fmt.Errorf("cannot decode the response of '%s': %v", endpoint, err)
+		}
+		return nil, nil
+	}
+
+	errResp := &errorResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(errResp); err != nil || errResp.Error == "" {
+		return nil, fmt.Errorf("unexpected response from '%s' statuscode=%d, status=%s",
+			endpoint, resp.StatusCode, resp.Status)
+	}
+
+	return errResp, nil
+}
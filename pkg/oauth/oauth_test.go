@@ -0,0 +1,123 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func writeJSON(w http.ResponseWriter, status int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(obj)
+}
+
+func TestDeviceAuthorizationURL(t *testing.T) {
+	actual := DeviceAuthorizationURL("https://sso.redhat.com/auth/realms/redhat-external/protocol/openid-connect/token")
+	expected := "https://sso.redhat.com/auth/realms/redhat-external/protocol/openid-connect/auth/device"
+	if actual != expected {
+		t.Errorf("expected %q, but got %q", expected, actual)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid_request"})
+			return
+		}
+		if r.Form.Get("grant_type") != grantTypeClientCredentials {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "unsupported_grant_type"})
+			return
+		}
+		if r.Form.Get("client_id") != "sa" || r.Form.Get("client_secret") != "secret" {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid_client"})
+			return
+		}
+		writeJSON(w, http.StatusOK, Token{AccessToken: "access", TokenType: "Bearer"})
+	}))
+	defer server.Close()
+
+	token, err := ClientCredentials(context.TODO(), server.Client(), server.URL, "sa", "secret", []string{"openid"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token.AccessToken != "access" {
+		t.Errorf("unexpected token %v", token)
+	}
+
+	if _, err := ClientCredentials(context.TODO(), server.Client(), server.URL, "sa", "wrong", nil); err == nil {
+		t.Errorf("expected error with invalid client")
+	}
+}
+
+func TestDeviceFlow(t *testing.T) {
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth/device", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("client_id") != "cli" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid_client"})
+			return
+		}
+		writeJSON(w, http.StatusOK, DeviceAuthorization{
+			DeviceCode:      "device",
+			UserCode:        "ABCD-EFGH",
+			VerificationURI: "https://sso.example.com/device",
+			ExpiresIn:       60,
+			Interval:        1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != grantTypeDeviceCode ||
+			r.Form.Get("device_code") != "device" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid_grant"})
+			return
+		}
+		polls++
+		if polls < 2 {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "authorization_pending"})
+			return
+		}
+		writeJSON(w, http.StatusOK, Token{AccessToken: "access", RefreshToken: "refresh"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tokenURL := server.URL + "/token"
+	authorization, err := RequestDeviceAuthorization(
+		context.TODO(), server.Client(), DeviceAuthorizationURL(tokenURL), "cli", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if authorization.UserCode != "ABCD-EFGH" {
+		t.Errorf("unexpected authorization %v", authorization)
+	}
+
+	token, err := PollDeviceToken(context.TODO(), server.Client(), tokenURL, "cli", authorization)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token.AccessToken != "access" || token.RefreshToken != "refresh" {
+		t.Errorf("unexpected token %v", token)
+	}
+	if polls != 2 {
+		t.Errorf("expected 2 polls, but got %d", polls)
+	}
+}
+
+func TestDeviceFlowDenied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "access_denied"})
+	}))
+	defer server.Close()
+
+	_, err := PollDeviceToken(context.TODO(), server.Client(), server.URL, "cli", &DeviceAuthorization{
+		DeviceCode: "device",
+		Interval:   1,
+	})
+	if err == nil {
+		t.Errorf("expected error when the authorization is denied")
+	}
+}
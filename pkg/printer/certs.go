@@ -0,0 +1,62 @@
+package printer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skeeey/xcm-cli/pkg/cert"
+)
+
+// CertificatesTable describes how the inspected certificates are printed.
+var CertificatesTable = Table{
+	Columns: []Column{
+		{Header: "NAME", Value: certValue(func(c cert.CertificateInfo) string { return c.Name })},
+		{Header: "SUBJECT", Value: certValue(func(c cert.CertificateInfo) string { return c.Subject })},
+		{Header: "ISSUER", Value: certValue(func(c cert.CertificateInfo) string { return c.Issuer })},
+		{Header: "SANS", Value: certValue(func(c cert.CertificateInfo) string { return strings.Join(c.SANs, ",") })},
+		{Header: "REMAINING", Value: certValue(func(c cert.CertificateInfo) string { return remaining(c.Remaining()) })},
+		{Header: "STATUS", Value: certValue(func(c cert.CertificateInfo) string { return c.Status })},
+	},
+	WideColumns: []Column{
+		{Header: "NAME", Value: certValue(func(c cert.CertificateInfo) string { return c.Name })},
+		{Header: "SUBJECT", Value: certValue(func(c cert.CertificateInfo) string { return c.Subject })},
+		{Header: "ISSUER", Value: certValue(func(c cert.CertificateInfo) string { return c.Issuer })},
+		{Header: "SANS", Value: certValue(func(c cert.CertificateInfo) string { return strings.Join(c.SANs, ",") })},
+		{Header: "NOT BEFORE", Value: certValue(func(c cert.CertificateInfo) string {
+			return c.NotBefore.UTC().Format(time.RFC3339)
+		})},
+		{Header: "NOT AFTER", Value: certValue(func(c cert.CertificateInfo) string {
+			return c.NotAfter.UTC().Format(time.RFC3339)
+		})},
+		{Header: "REMAINING", Value: certValue(func(c cert.CertificateInfo) string { return remaining(c.Remaining()) })},
+		{Header: "STATUS", Value: certValue(func(c cert.CertificateInfo) string { return c.Status })},
+	},
+	Name: certValue(func(c cert.CertificateInfo) string { return c.Name }),
+}
+
+func certValue(value func(c cert.CertificateInfo) string) func(obj interface{}) string {
+	return func(obj interface{}) string {
+		switch info := obj.(type) {
+		case cert.CertificateInfo:
+			return value(info)
+		case *cert.CertificateInfo:
+			return value(*info)
+		}
+		return ""
+	}
+}
+
+// remaining returns the remaining validity in days and hours, e.g. '89d23h'.
+func remaining(d time.Duration) string {
+	if d <= 0 {
+		return "0"
+	}
+
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	if days == 0 {
+		return d.Round(time.Minute).String()
+	}
+	return fmt.Sprintf("%dd%dh", days, hours)
+}
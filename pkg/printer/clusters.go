@@ -0,0 +1,41 @@
+package printer
+
+import (
+	"fmt"
+
+	"github.com/skeeey/xcm-cli/pkg/rest"
+)
+
+// ClustersTable describes how the xCM clusters are printed.
+var ClustersTable = Table{
+	Columns: []Column{
+		{Header: "ID", Value: clusterValue(func(c rest.Cluster) string { return c.ID })},
+		{Header: "STATUS", Value: clusterValue(func(c rest.Cluster) string { return c.Status })},
+		{Header: "TYPE", Value: clusterValue(func(c rest.Cluster) string { return c.Type })},
+		{Header: "VERSION", Value: clusterValue(func(c rest.Cluster) string { return c.Version })},
+		{Header: "PLATFORM", Value: clusterValue(func(c rest.Cluster) string {
+			return fmt.Sprintf("%s (%s)", c.Platform, c.Region)
+		})},
+	},
+	WideColumns: []Column{
+		{Header: "ID", Value: clusterValue(func(c rest.Cluster) string { return c.ID })},
+		{Header: "STATUS", Value: clusterValue(func(c rest.Cluster) string { return c.Status })},
+		{Header: "TYPE", Value: clusterValue(func(c rest.Cluster) string { return c.Type })},
+		{Header: "VERSION", Value: clusterValue(func(c rest.Cluster) string { return c.Version })},
+		{Header: "PLATFORM", Value: clusterValue(func(c rest.Cluster) string { return c.Platform })},
+		{Header: "REGION", Value: clusterValue(func(c rest.Cluster) string { return c.Region })},
+	},
+	Name: clusterValue(func(c rest.Cluster) string { return fmt.Sprintf("cluster/%s", c.ID) }),
+}
+
+func clusterValue(value func(c rest.Cluster) string) func(obj interface{}) string {
+	return func(obj interface{}) string {
+		switch cluster := obj.(type) {
+		case rest.Cluster:
+			return value(cluster)
+		case *rest.Cluster:
+			return value(*cluster)
+		}
+		return ""
+	}
+}
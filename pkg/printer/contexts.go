@@ -0,0 +1,38 @@
+package printer
+
+import (
+	"github.com/skeeey/xcm-cli/pkg/configs"
+)
+
+// ContextsTable describes how the control plane contexts are printed, the current context is
+// marked with '*'.
+func ContextsTable(current string) Table {
+	columns := []Column{
+		{Header: "CURRENT", Value: contextValue(func(c configs.ControlPlaneContext) string {
+			if c.ID == current {
+				return "*"
+			}
+			return ""
+		})},
+		{Header: "ID", Value: contextValue(func(c configs.ControlPlaneContext) string { return c.ID })},
+		{Header: "SERVER", Value: contextValue(func(c configs.ControlPlaneContext) string { return c.Server })},
+	}
+
+	return Table{
+		Columns:     columns,
+		WideColumns: columns,
+		Name:        contextValue(func(c configs.ControlPlaneContext) string { return c.ID }),
+	}
+}
+
+func contextValue(value func(c configs.ControlPlaneContext) string) func(obj interface{}) string {
+	return func(obj interface{}) string {
+		switch context := obj.(type) {
+		case configs.ControlPlaneContext:
+			return value(context)
+		case *configs.ControlPlaneContext:
+			return value(*context)
+		}
+		return ""
+	}
+}
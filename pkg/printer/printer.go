@@ -1,24 +1,285 @@
 package printer
 
 import (
+	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
 
-	"github.com/skeeey/xcm-cli/pkg/rest"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
 )
 
-// ID       string `json:"id"`
-// 	Status   string `json:"status"`
-// 	Type     string `json:"type"`
-// 	Version  string `json:"version"`
-// 	Platform string `json:"platform"`
-// 	Region   string `json:"region"`
+const (
+	FormatTable         = "table"
+	FormatWide          = "wide"
+	FormatJSON          = "json"
+	FormatYAML          = "yaml"
+	FormatName          = "name"
+	FormatJSONPath      = "jsonpath="
+	FormatCustomColumns = "custom-columns="
+)
+
+// Printer prints an object or a slice of objects to the writer.
+type Printer interface {
+	Print(w io.Writer, obj interface{}) error
+}
+
+// Column is a column of the table, the value of the column is read from the object.
+type Column struct {
+	Header string
+	Value  func(obj interface{}) string
+}
+
+// Table describes how the objects are printed with the table, wide and name formats.
+type Table struct {
+	Columns     []Column
+	WideColumns []Column
+	Name        func(obj interface{}) string
+}
+
+// AddFlag adds the output flag to the given set of command line flags.
+func AddFlag(flags *pflag.FlagSet) {
+	flags.StringVarP(
+		&output,
+		"output",
+		"o",
+		FormatTable,
+		"Output format. One of: table|wide|json|yaml|name|jsonpath=<template>|custom-columns=<spec>.",
+	)
+}
+
+// Output returns the output format.
+func Output() string {
+	return output
+}
+
+// NewPrinter creates a printer for the given output format, the table is used by the table, wide and
+// name formats.
+func NewPrinter(format string, table Table) (Printer, error) {
+	switch {
+	case format == "" || format == FormatTable:
+		return &tablePrinter{columns: table.Columns}, nil
+	case format == FormatWide:
+		return &tablePrinter{columns: table.WideColumns}, nil
+	case format == FormatJSON:
+		return &jsonPrinter{}, nil
+	case format == FormatYAML:
+		return &yamlPrinter{}, nil
+	case format == FormatName:
+		return &namePrinter{name: table.Name}, nil
+	case strings.HasPrefix(format, FormatJSONPath):
+		return newJSONPathPrinter(strings.TrimPrefix(format, FormatJSONPath))
+	case strings.HasPrefix(format, FormatCustomColumns):
+		return newCustomColumnsPrinter(strings.TrimPrefix(format, FormatCustomColumns))
+	}
+
+	return nil, fmt.Errorf("unsupported output format %q", format)
+}
+
+type tablePrinter struct {
+	columns []Column
+}
+
+func (p *tablePrinter) Print(w io.Writer, obj interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	headers := []string{}
+	for _, column := range p.columns {
+		headers = append(headers, column.Header)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, item := range items(obj) {
+		values := []string{}
+		for _, column := range p.columns {
+			values = append(values, column.Value(item))
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+type jsonPrinter struct{}
+
+func (p *jsonPrinter) Print(w io.Writer, obj interface{}) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+type yamlPrinter struct{}
+
+func (p *yamlPrinter) Print(w io.Writer, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+type namePrinter struct {
+	name func(obj interface{}) string
+}
+
+func (p *namePrinter) Print(w io.Writer, obj interface{}) error {
+	if p.name == nil {
+		return fmt.Errorf("the name output format is not supported")
+	}
+
+	for _, item := range items(obj) {
+		fmt.Fprintln(w, p.name(item))
+	}
+
+	return nil
+}
+
+type jsonPathPrinter struct {
+	jsonPath *jsonpath.JSONPath
+}
+
+func newJSONPathPrinter(template string) (*jsonPathPrinter, error) {
+	jsonPath := jsonpath.New("output")
+	if err := jsonPath.Parse(template); err != nil {
+		return nil, fmt.Errorf("invalid jsonpath template %q: %v", template, err)
+	}
+
+	return &jsonPathPrinter{jsonPath: jsonPath}, nil
+}
+
+func (p *jsonPathPrinter) Print(w io.Writer, obj interface{}) error {
+	data, err := toJSONObject(obj)
+	if err != nil {
+		return err
+	}
+
+	if err := p.jsonPath.Execute(w, data); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w)
+	return err
+}
 
-func PrintClustersTable(clusters ...rest.Cluster) {
-	fmt.Fprintln(os.Stdout, "ID\t\t\t\t\t Status\t\t Type\t Version\t\t Platform")
+type customColumn struct {
+	header   string
+	jsonPath *jsonpath.JSONPath
+}
+
+type customColumnsPrinter struct {
+	columns []customColumn
+}
 
-	for _, cluster := range clusters {
-		platform := fmt.Sprintf("%s (%s)", cluster.Platform, cluster.Region)
-		fmt.Fprintln(os.Stdout, cluster.ID, "\t", cluster.Status, "\t", cluster.Type, "\t", cluster.Version, "\t", platform)
+// newCustomColumnsPrinter creates a printer with the spec like `ID:.id,STATUS:.status`.
+func newCustomColumnsPrinter(spec string) (*customColumnsPrinter, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("custom-columns format specified but no custom columns given")
 	}
+
+	columns := []customColumn{}
+	for _, part := range strings.Split(spec, ",") {
+		header, path, found := strings.Cut(part, ":")
+		if !found || header == "" || path == "" {
+			return nil, fmt.Errorf("unexpected custom-columns spec %q, expected <header>:<json-path-expr>", part)
+		}
+
+		if !strings.HasPrefix(path, "{") {
+			if !strings.HasPrefix(path, ".") {
+				path = "." + path
+			}
+			path = fmt.Sprintf("{%s}", path)
+		}
+
+		jsonPath := jsonpath.New(header).AllowMissingKeys(true)
+		if err := jsonPath.Parse(path); err != nil {
+			return nil, fmt.Errorf("invalid json path %q of column %q: %v", path, header, err)
+		}
+
+		columns = append(columns, customColumn{header: header, jsonPath: jsonPath})
+	}
+
+	return &customColumnsPrinter{columns: columns}, nil
 }
+
+func (p *customColumnsPrinter) Print(w io.Writer, obj interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	headers := []string{}
+	for _, column := range p.columns {
+		headers = append(headers, column.header)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, item := range items(obj) {
+		data, err := toJSONObject(item)
+		if err != nil {
+			return err
+		}
+
+		values := []string{}
+		for _, column := range p.columns {
+			results, err := column.jsonPath.FindResults(data)
+			if err != nil {
+				return err
+			}
+
+			value := []string{}
+			for _, result := range results {
+				for _, r := range result {
+					value = append(value, fmt.Sprintf("%v", r.Interface()))
+				}
+			}
+			if len(value) == 0 {
+				value = append(value, "<none>")
+			}
+
+			values = append(values, strings.Join(value, ","))
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// items returns the items of a slice, or the object itself if the object is not a slice.
+func items(obj interface{}) []interface{} {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Slice {
+		return []interface{}{obj}
+	}
+
+	items := []interface{}{}
+	for i := 0; i < value.Len(); i++ {
+		items = append(items, value.Index(i).Interface())
+	}
+
+	return items
+}
+
+// toJSONObject converts the object to its generic json representation, so that the json paths can
+// use the json field names.
+func toJSONObject(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonObj interface{}
+	if err := json.Unmarshal(data, &jsonObj); err != nil {
+		return nil, err
+	}
+
+	return jsonObj, nil
+}
+
+var output string
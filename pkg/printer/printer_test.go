@@ -0,0 +1,108 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/skeeey/xcm-cli/pkg/rest"
+)
+
+var clusters = []rest.Cluster{
+	{ID: "c1", Status: "Available", Type: "EKS", Version: "v1.23.5", Platform: "AWS", Region: "us-east-2"},
+	{ID: "a-long-cluster-id", Status: "Unknown", Type: "unknown", Version: "unknown", Platform: "unknown", Region: "unknown"},
+}
+
+func print(t *testing.T, format string, obj interface{}) string {
+	p, err := NewPrinter(format, ClustersTable)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := p.Print(buf, obj); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return buf.String()
+}
+
+func TestPrinter(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   string
+		obj      interface{}
+		expected string
+	}{
+		{
+			name:   "table",
+			format: "table",
+			obj:    clusters,
+			expected: "ID                 STATUS     TYPE     VERSION  PLATFORM\n" +
+				"c1                 Available  EKS      v1.23.5  AWS (us-east-2)\n" +
+				"a-long-cluster-id  Unknown    unknown  unknown  unknown (unknown)\n",
+		},
+		{
+			name:   "wide",
+			format: "wide",
+			obj:    &clusters[0],
+			expected: "ID  STATUS     TYPE  VERSION  PLATFORM  REGION\n" +
+				"c1  Available  EKS   v1.23.5  AWS       us-east-2\n",
+		},
+		{
+			name:     "name",
+			format:   "name",
+			obj:      clusters,
+			expected: "cluster/c1\ncluster/a-long-cluster-id\n",
+		},
+		{
+			name:     "yaml",
+			format:   "yaml",
+			obj:      clusters[0],
+			expected: "id: c1\nplatform: AWS\nregion: us-east-2\nstatus: Available\ntype: EKS\nversion: v1.23.5\n",
+		},
+		{
+			name:     "jsonpath",
+			format:   "jsonpath={[*].id}",
+			obj:      clusters,
+			expected: "c1 a-long-cluster-id\n",
+		},
+		{
+			name:   "custom-columns",
+			format: "custom-columns=ID:.id,REGION:region",
+			obj:    clusters,
+			expected: "ID                 REGION\n" +
+				"c1                 us-east-2\n" +
+				"a-long-cluster-id  unknown\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := print(t, c.format, c.obj); actual != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJSONPrinter(t *testing.T) {
+	actual := []rest.Cluster{}
+	if err := json.Unmarshal([]byte(print(t, "json", clusters)), &actual); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(actual) != 2 || actual[1] != clusters[1] {
+		t.Errorf("unexpected clusters %v", actual)
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	if _, err := NewPrinter("xml", ClustersTable); err == nil {
+		t.Errorf("expected error")
+	}
+
+	if _, err := NewPrinter("custom-columns=ID", ClustersTable); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,82 @@
+package resource
+
+import (
+	"context"
+
+	ocmoperatorclient "open-cluster-management.io/api/client/operator/clientset/versioned"
+
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/skeeey/xcm-cli/pkg/recorder"
+)
+
+// Applier applies and deletes the objects of any kind. The kinds that are supported by ApplyResources
+// are handled with their typed clients, the other kinds are applied with the server-side apply and
+// deleted through the dynamic client.
+type Applier struct {
+	KubeClient          kubernetes.Interface
+	APIExtensionsClient apiextensionsclient.Interface
+	OperatorClient      ocmoperatorclient.Interface
+	DynamicClient       dynamic.Interface
+
+	// ForceConflicts takes the ownership of the fields that are managed by the other field managers,
+	// otherwise a ConflictError is returned.
+	ForceConflicts bool
+}
+
+// Apply applies the objects in the given order, it stops at the first error, so that the objects
+// that depend on the failed object, e.g. the objects in a namespace, are not applied.
+func (a *Applier) Apply(ctx context.Context, objs ...runtime.Object) error {
+	logger := &recorder.DumbRecorder{}
+	for _, obj := range objs {
+		handled, err := applyTyped(ctx, a.KubeClient, a.APIExtensionsClient, a.OperatorClient, logger, obj)
+		switch {
+		case handled:
+		case a.DynamicClient == nil:
+			err = unsupportedKindError(obj)
+		default:
+			err = ServerSideApply(ctx, a.DynamicClient, obj, a.ForceConflicts)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes the objects in the given order, the objects that are not found are ignored.
+func (a *Applier) Delete(ctx context.Context, objs ...runtime.Object) error {
+	errs := []error{}
+	for _, obj := range objs {
+		handled, err := deleteTyped(ctx, a.KubeClient, a.APIExtensionsClient, a.OperatorClient, obj)
+		switch {
+		case handled:
+		case a.DynamicClient == nil:
+			err = unsupportedKindError(obj)
+		default:
+			err = a.deleteUnstructured(ctx, obj)
+		}
+		if errors.IsNotFound(err) {
+			continue
+		}
+		errs = append(errs, err)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func (a *Applier) deleteUnstructured(ctx context.Context, obj runtime.Object) error {
+	required, err := ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	return ResourceInterface(a.DynamicClient, required).Delete(ctx, required.GetName(), metav1.DeleteOptions{})
+}
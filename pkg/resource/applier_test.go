@@ -0,0 +1,145 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newNamespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+}
+
+func newIngress(name string) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "xcm"},
+	}
+}
+
+func TestApplyResourcesUnsupportedKind(t *testing.T) {
+	err := ApplyResources(context.TODO(), fake.NewSimpleClientset(), nil, nil, newNamespace("xcm"), newIngress("cp"))
+	if err == nil {
+		t.Fatalf("expected error with an unsupported kind")
+	}
+
+	if err := DeleteResources(context.TODO(), fake.NewSimpleClientset(), nil, nil, newIngress("cp")); err == nil {
+		t.Errorf("expected error with an unsupported kind")
+	}
+}
+
+func TestApplier(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+
+	patches := []clienttesting.PatchActionImpl{}
+	dynamicClient.PrependReactor("patch", "ingresses", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clienttesting.PatchActionImpl)
+		patches = append(patches, patch)
+		if patch.GetName() == "conflicted" {
+			return true, nil, apierrors.NewApplyConflict([]metav1.StatusCause{{
+				Type:    metav1.CauseTypeFieldManagerConflict,
+				Message: `conflict with "kubectl" using networking.k8s.io/v1`,
+				Field:   ".spec.rules",
+			}}, "Apply failed with 1 conflict")
+		}
+		return true, nil, nil
+	})
+
+	applier := &Applier{KubeClient: kubeClient, DynamicClient: dynamicClient}
+	if err := applier.Apply(context.TODO(), newNamespace("xcm"), newIngress("cp")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := kubeClient.CoreV1().Namespaces().Get(context.TODO(), "xcm", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the namespace is applied with the typed client, but got %v", err)
+	}
+	if len(patches) != 1 || patches[0].GetPatchType() != "application/apply-patch+yaml" {
+		t.Fatalf("expected the ingress is applied with the server-side apply, but got %v", patches)
+	}
+
+	err := applier.Apply(context.TODO(), newIngress("conflicted"))
+	conflict := &ConflictError{}
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a conflict error, but got %v", err)
+	}
+	if conflict.Object != "ingress/conflicted" || len(conflict.Conflicts) != 1 {
+		t.Errorf("unexpected conflict %v", conflict)
+	}
+
+	// the ingress that is not found is ignored
+	if err := applier.Delete(context.TODO(), newIngress("cp"), newNamespace("xcm")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := (&Applier{KubeClient: kubeClient}).Apply(context.TODO(), newIngress("cp")); err == nil {
+		t.Errorf("expected error without the dynamic client")
+	}
+}
+
+func TestApplyRoles(t *testing.T) {
+	role := &rbacv1.Role{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "xcm"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}},
+	}
+	newBinding := func(roleKind, roleName string) *rbacv1.RoleBinding {
+		return &rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "xcm"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: roleKind, Name: roleName},
+			Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "agent-sa", Namespace: "xcm"}},
+		}
+	}
+
+	kubeClient := fake.NewSimpleClientset(newBinding("ClusterRole", "admin"))
+	if err := ApplyResources(context.TODO(), kubeClient, nil, nil, role, newBinding("Role", "agent")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := kubeClient.RbacV1().Roles("xcm").Get(context.TODO(), "agent", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the role is applied, but got %v", err)
+	}
+	// the role of a binding is immutable, the binding is recreated
+	binding, err := kubeClient.RbacV1().RoleBindings("xcm").Get(context.TODO(), "agent", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if binding.RoleRef.Kind != "Role" || binding.RoleRef.Name != "agent" {
+		t.Errorf("unexpected role of the binding %v", binding.RoleRef)
+	}
+	deleted := false
+	for _, action := range kubeClient.Actions() {
+		if action.GetVerb() == "delete" && action.GetResource().Resource == "rolebindings" {
+			deleted = true
+		}
+	}
+	if !deleted {
+		t.Errorf("expected the binding is recreated, but got %v", kubeClient.Actions())
+	}
+
+	if err := DeleteResources(context.TODO(), kubeClient, nil, nil, newBinding("Role", "agent"), role); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGroupVersionResource(t *testing.T) {
+	gvr := GroupVersionResource(schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"})
+	if gvr.Resource != "ingresses" {
+		t.Errorf("unexpected resource %v", gvr)
+	}
+}
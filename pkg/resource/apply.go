@@ -2,6 +2,7 @@ package resource
 
 import (
 	"context"
+	"fmt"
 
 	ocmoperatorclient "open-cluster-management.io/api/client/operator/clientset/versioned"
 	ocmoperatorv1 "open-cluster-management.io/api/operator/v1"
@@ -24,13 +25,19 @@ import (
 )
 
 // ApplyResources apply resources, includes:
+// - service
 // - serviceaccount
 // - secret
+// - namespace
 // - deployment
 // - clusterrole
-// - clusterrolebinding,
+// - clusterrolebinding
+// - role
+// - rolebinding
 // - crdv1
 // - klusterlet
+// An error is returned for the objects of the other kinds, use Applier to apply them with the
+// server-side apply.
 func ApplyResources(ctx context.Context,
 	kubeClient kubernetes.Interface,
 	apiExtensionsClient apiextensionsclient.Interface,
@@ -39,43 +46,69 @@ func ApplyResources(ctx context.Context,
 	logger := &recorder.DumbRecorder{}
 	errs := []error{}
 	for _, obj := range objs {
-		switch required := obj.(type) {
-		case *corev1.Service:
-			_, _, err := resourceapply.ApplyService(ctx, kubeClient.CoreV1(), logger, required)
-			errs = append(errs, err)
-		case *corev1.ServiceAccount:
-			_, _, err := resourceapply.ApplyServiceAccount(ctx, kubeClient.CoreV1(), logger, required)
-			errs = append(errs, err)
-		case *corev1.Secret:
-			_, _, err := resourceapply.ApplySecret(ctx, kubeClient.CoreV1(), logger, required)
-			errs = append(errs, err)
-		case *corev1.Namespace:
-			_, _, err := resourceapply.ApplyNamespace(ctx, kubeClient.CoreV1(), logger, required)
-			errs = append(errs, err)
-		case *appsv1.Deployment:
-			errs = append(errs, applyDeployment(ctx, kubeClient, logger, required))
-		case *rbacv1.ClusterRole:
-			_, _, err := resourceapply.ApplyClusterRole(ctx, kubeClient.RbacV1(), logger, required)
-			errs = append(errs, err)
-		case *rbacv1.ClusterRoleBinding:
-			_, _, err := resourceapply.ApplyClusterRoleBinding(ctx, kubeClient.RbacV1(), logger, required)
-			errs = append(errs, err)
-		case *crdv1.CustomResourceDefinition:
-			_, _, err := resourceapply.ApplyCustomResourceDefinitionV1(
-				ctx,
-				apiExtensionsClient.ApiextensionsV1(),
-				logger,
-				required,
-			)
-			errs = append(errs, err)
-		case *ocmoperatorv1.Klusterlet:
-			errs = append(errs, applyKlusterlet(ctx, operatorClient, logger, required))
+		handled, err := applyTyped(ctx, kubeClient, apiExtensionsClient, operatorClient, logger, obj)
+		if !handled {
+			err = unsupportedKindError(obj)
 		}
+		errs = append(errs, err)
 	}
 
 	return utilerrors.NewAggregate(errs)
 }
 
+// applyTyped applies the object with its typed client, it returns false if the kind of the object is
+// not supported.
+func applyTyped(ctx context.Context,
+	kubeClient kubernetes.Interface,
+	apiExtensionsClient apiextensionsclient.Interface,
+	operatorClient ocmoperatorclient.Interface,
+	logger events.Recorder,
+	obj runtime.Object) (bool, error) {
+	var err error
+	switch required := obj.(type) {
+	case *corev1.Service:
+		_, _, err = resourceapply.ApplyService(ctx, kubeClient.CoreV1(), logger, required)
+	case *corev1.ServiceAccount:
+		_, _, err = resourceapply.ApplyServiceAccount(ctx, kubeClient.CoreV1(), logger, required)
+	case *corev1.Secret:
+		_, _, err = resourceapply.ApplySecret(ctx, kubeClient.CoreV1(), logger, required)
+	case *corev1.Namespace:
+		_, _, err = resourceapply.ApplyNamespace(ctx, kubeClient.CoreV1(), logger, required)
+	case *appsv1.Deployment:
+		err = applyDeployment(ctx, kubeClient, logger, required)
+	case *rbacv1.ClusterRole:
+		_, _, err = resourceapply.ApplyClusterRole(ctx, kubeClient.RbacV1(), logger, required)
+	case *rbacv1.ClusterRoleBinding:
+		err = applyClusterRoleBinding(ctx, kubeClient, logger, required)
+	case *rbacv1.Role:
+		_, _, err = resourceapply.ApplyRole(ctx, kubeClient.RbacV1(), logger, required)
+	case *rbacv1.RoleBinding:
+		err = applyRoleBinding(ctx, kubeClient, logger, required)
+	case *crdv1.CustomResourceDefinition:
+		_, _, err = resourceapply.ApplyCustomResourceDefinitionV1(
+			ctx,
+			apiExtensionsClient.ApiextensionsV1(),
+			logger,
+			required,
+		)
+	case *ocmoperatorv1.Klusterlet:
+		err = applyKlusterlet(ctx, operatorClient, logger, required)
+	default:
+		return false, nil
+	}
+
+	return true, err
+}
+
+// unsupportedKindError returns the error of an object whose kind cannot be handled.
+func unsupportedKindError(obj runtime.Object) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		return fmt.Errorf("the object %T is not supported", obj)
+	}
+	return fmt.Errorf("the kind %s is not supported", gvk)
+}
+
 func applyDeployment(ctx context.Context, kubeClient kubernetes.Interface, recorder events.Recorder,
 	required *appsv1.Deployment) error {
 	existing, err := kubeClient.AppsV1().Deployments(required.Namespace).Get(ctx, required.Name, metav1.GetOptions{})
@@ -91,6 +124,51 @@ func applyDeployment(ctx context.Context, kubeClient kubernetes.Interface, recor
 	return err
 }
 
+// applyClusterRoleBinding applies the cluster role binding, the existing binding is recreated if its
+// role is changed, because the role of a binding is immutable.
+func applyClusterRoleBinding(ctx context.Context, kubeClient kubernetes.Interface, recorder events.Recorder,
+	required *rbacv1.ClusterRoleBinding) error {
+	existing, err := kubeClient.RbacV1().ClusterRoleBindings().Get(ctx, required.Name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return err
+	case !sameRoleRef(existing.RoleRef, required.RoleRef):
+		if err := kubeClient.RbacV1().ClusterRoleBindings().Delete(ctx, required.Name, metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+	}
+
+	_, _, err = resourceapply.ApplyClusterRoleBinding(ctx, kubeClient.RbacV1(), recorder, required)
+	return err
+}
+
+// applyRoleBinding applies the role binding, the existing binding is recreated if its role is
+// changed, because the role of a binding is immutable.
+func applyRoleBinding(ctx context.Context, kubeClient kubernetes.Interface, recorder events.Recorder,
+	required *rbacv1.RoleBinding) error {
+	existing, err := kubeClient.RbacV1().RoleBindings(required.Namespace).Get(ctx, required.Name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return err
+	case !sameRoleRef(existing.RoleRef, required.RoleRef):
+		if err := kubeClient.RbacV1().RoleBindings(required.Namespace).Delete(
+			ctx, required.Name, metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+	}
+
+	_, _, err = resourceapply.ApplyRoleBinding(ctx, kubeClient.RbacV1(), recorder, required)
+	return err
+}
+
+// sameRoleRef compares the kind and the name of the roles, the api group is ignored, it's defaulted by
+// the server.
+func sameRoleRef(existing, required rbacv1.RoleRef) bool {
+	return existing.Kind == required.Kind && existing.Name == required.Name
+}
+
 func applyKlusterlet(ctx context.Context,
 	client ocmoperatorclient.Interface, recorder events.Recorder, required *ocmoperatorv1.Klusterlet) error {
 	existing, err := client.OperatorV1().Klusterlets().Get(ctx, required.Name, metav1.GetOptions{})
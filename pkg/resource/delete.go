@@ -0,0 +1,81 @@
+package resource
+
+import (
+	"context"
+
+	ocmoperatorclient "open-cluster-management.io/api/client/operator/clientset/versioned"
+	ocmoperatorv1 "open-cluster-management.io/api/operator/v1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DeleteResources deletes resources in the given order, the resources that are not found are ignored.
+// The supported resources are same as ApplyResources, an error is returned for the objects of the
+// other kinds.
+func DeleteResources(ctx context.Context,
+	kubeClient kubernetes.Interface,
+	apiExtensionsClient apiextensionsclient.Interface,
+	operatorClient ocmoperatorclient.Interface,
+	objs ...runtime.Object) error {
+	errs := []error{}
+	for _, obj := range objs {
+		handled, err := deleteTyped(ctx, kubeClient, apiExtensionsClient, operatorClient, obj)
+		if !handled {
+			err = unsupportedKindError(obj)
+		}
+		if errors.IsNotFound(err) {
+			continue
+		}
+		errs = append(errs, err)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// deleteTyped deletes the object with its typed client, it returns false if the kind of the object is
+// not supported.
+func deleteTyped(ctx context.Context,
+	kubeClient kubernetes.Interface,
+	apiExtensionsClient apiextensionsclient.Interface,
+	operatorClient ocmoperatorclient.Interface,
+	obj runtime.Object) (bool, error) {
+	var err error
+	switch required := obj.(type) {
+	case *corev1.Service:
+		err = kubeClient.CoreV1().Services(required.Namespace).Delete(ctx, required.Name, metav1.DeleteOptions{})
+	case *corev1.ServiceAccount:
+		err = kubeClient.CoreV1().ServiceAccounts(required.Namespace).Delete(ctx, required.Name, metav1.DeleteOptions{})
+	case *corev1.Secret:
+		err = kubeClient.CoreV1().Secrets(required.Namespace).Delete(ctx, required.Name, metav1.DeleteOptions{})
+	case *corev1.Namespace:
+		err = kubeClient.CoreV1().Namespaces().Delete(ctx, required.Name, metav1.DeleteOptions{})
+	case *appsv1.Deployment:
+		err = kubeClient.AppsV1().Deployments(required.Namespace).Delete(ctx, required.Name, metav1.DeleteOptions{})
+	case *rbacv1.ClusterRole:
+		err = kubeClient.RbacV1().ClusterRoles().Delete(ctx, required.Name, metav1.DeleteOptions{})
+	case *rbacv1.ClusterRoleBinding:
+		err = kubeClient.RbacV1().ClusterRoleBindings().Delete(ctx, required.Name, metav1.DeleteOptions{})
+	case *rbacv1.Role:
+		err = kubeClient.RbacV1().Roles(required.Namespace).Delete(ctx, required.Name, metav1.DeleteOptions{})
+	case *rbacv1.RoleBinding:
+		err = kubeClient.RbacV1().RoleBindings(required.Namespace).Delete(ctx, required.Name, metav1.DeleteOptions{})
+	case *crdv1.CustomResourceDefinition:
+		err = apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Delete(
+			ctx, required.Name, metav1.DeleteOptions{})
+	case *ocmoperatorv1.Klusterlet:
+		err = operatorClient.OperatorV1().Klusterlets().Delete(ctx, required.Name, metav1.DeleteOptions{})
+	default:
+		return false, nil
+	}
+
+	return true, err
+}
@@ -0,0 +1,263 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// InstallLabel is the label of the objects that are owned by xcm, its value is the name of the install
+// that applies the objects, e.g. 'connector'. Only the objects with this label are pruned.
+const InstallLabel = "xcm.open-cluster-management.io/install"
+
+// inventoryDataKey is the key of the applied objects in the inventory config map.
+const inventoryDataKey = "objects"
+
+// ObjectRef identifies an applied object.
+type ObjectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func (r ObjectRef) String() string {
+	return fmt.Sprintf("%s/%s", strings.ToLower(r.Kind), r.Name)
+}
+
+// Inventory records the objects that are applied by an install in a config map, so that the objects
+// that are no longer rendered by a later version can be pruned.
+type Inventory struct {
+	// Install is the name of the install, the objects of the install are labeled with it.
+	Install string
+
+	// Namespace is the namespace of the inventory config map, it's the namespace of the install.
+	Namespace string
+}
+
+// name returns the name of the inventory config map.
+func (i *Inventory) name() string {
+	return fmt.Sprintf("xcm-inventory-%s", i.Install)
+}
+
+// Label labels the objects with the install label.
+func (i *Inventory) Label(objs ...runtime.Object) []runtime.Object {
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+
+		labels := accessor.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[InstallLabel] = i.Install
+		accessor.SetLabels(labels)
+	}
+
+	return objs
+}
+
+// Load returns the objects that are recorded in the inventory, it's empty if the inventory is not
+// found.
+func (i *Inventory) Load(ctx context.Context, a *Applier) ([]ObjectRef, error) {
+	cm, err := a.KubeClient.CoreV1().ConfigMaps(i.Namespace).Get(ctx, i.name(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refs := []ObjectRef{}
+	if err := json.Unmarshal([]byte(cm.Data[inventoryDataKey]), &refs); err != nil {
+		return nil, fmt.Errorf("failed to parse the inventory %s/%s: %v", i.Namespace, i.name(), err)
+	}
+
+	return refs, nil
+}
+
+// Save records the objects in the inventory, the recorded objects are replaced. It should be called
+// only after the recorded objects that are not in the given objects are pruned.
+func (i *Inventory) Save(ctx context.Context, a *Applier, objs ...runtime.Object) error {
+	refs, err := objectRefs(objs)
+	if err != nil {
+		return err
+	}
+
+	return i.save(ctx, a, refs)
+}
+
+// Merge records the objects in the inventory, the recorded objects are kept, so that the objects that
+// are no longer rendered can still be pruned later.
+func (i *Inventory) Merge(ctx context.Context, a *Applier, objs ...runtime.Object) error {
+	recorded, err := i.Load(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	current, err := objectRefs(objs)
+	if err != nil {
+		return err
+	}
+
+	refs := append([]ObjectRef{}, recorded...)
+	seen := map[ObjectRef]bool{}
+	for _, ref := range recorded {
+		seen[ref] = true
+	}
+	for _, ref := range current {
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+
+	return i.save(ctx, a, refs)
+}
+
+func (i *Inventory) save(ctx context.Context, a *Applier, refs []ObjectRef) error {
+	data, err := json.Marshal(refs)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      i.name(),
+			Namespace: i.Namespace,
+			Labels:    map[string]string{InstallLabel: i.Install},
+		},
+		Data: map[string]string{inventoryDataKey: string(data)},
+	}
+
+	existing, err := a.KubeClient.CoreV1().ConfigMaps(i.Namespace).Get(ctx, cm.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = a.KubeClient.CoreV1().ConfigMaps(i.Namespace).Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	cm.ResourceVersion = existing.ResourceVersion
+	_, err = a.KubeClient.CoreV1().ConfigMaps(i.Namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// Prune deletes the objects that are recorded in the inventory but are not in the given objects, the
+// objects that are not labeled with the install are kept, e.g. an object that is taken over by
+// another tool. Each pruned object is reported to the writer, and the pruned objects are returned.
+func (i *Inventory) Prune(ctx context.Context, w io.Writer, a *Applier, objs ...runtime.Object) ([]ObjectRef, error) {
+	if a.DynamicClient == nil {
+		return nil, fmt.Errorf("the dynamic client is required to prune the objects")
+	}
+
+	recorded, err := i.Load(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := objectRefs(objs)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := map[ObjectRef]bool{}
+	for _, ref := range current {
+		rendered[ref] = true
+	}
+
+	pruned := []ObjectRef{}
+	errs := []error{}
+	// the objects are deleted in the reverse order that they were applied
+	for j := len(recorded) - 1; j >= 0; j-- {
+		ref := recorded[j]
+		if rendered[ref] {
+			continue
+		}
+
+		deleted, err := i.prune(ctx, a, ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to prune %s: %v", ref, err))
+			continue
+		}
+		if deleted {
+			fmt.Fprintf(w, "%s pruned\n", ref)
+			pruned = append(pruned, ref)
+		}
+	}
+
+	return pruned, utilerrors.NewAggregate(errs)
+}
+
+// prune deletes the object if it's labeled with the install, it returns false if the object is not
+// found or is not owned by the install.
+func (i *Inventory) prune(ctx context.Context, a *Applier, ref ObjectRef) (bool, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return false, err
+	}
+
+	gvr := GroupVersionResource(gv.WithKind(ref.Kind))
+	resourceClient := a.DynamicClient.Resource(gvr).Namespace(ref.Namespace)
+
+	obj, err := resourceClient.Get(ctx, ref.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if obj.GetLabels()[InstallLabel] != i.Install {
+		return false, nil
+	}
+
+	err = resourceClient.Delete(ctx, ref.Name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func objectRefs(objs []runtime.Object) ([]ObjectRef, error) {
+	refs := []ObjectRef{}
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if gvk.Empty() {
+			return nil, fmt.Errorf("the kind of the object %T is unknown", obj)
+		}
+
+		refs = append(refs, ObjectRef{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Namespace:  accessor.GetNamespace(),
+			Name:       accessor.GetName(),
+		})
+	}
+
+	return refs, nil
+}
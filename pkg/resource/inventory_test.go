@@ -0,0 +1,140 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newConfigMap(name string, labels map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "xcm", Labels: labels},
+	}
+}
+
+func mustToUnstructured(t *testing.T, obj runtime.Object) *unstructured.Unstructured {
+	u, err := ToUnstructured(obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return u
+}
+
+func TestInventoryLabel(t *testing.T) {
+	inventory := &Inventory{Install: "agent", Namespace: "xcm"}
+	objs := inventory.Label(newNamespace("xcm"), newConfigMap("cm", map[string]string{"app": "agent"}))
+
+	for _, obj := range objs {
+		labels := mustToUnstructured(t, obj).GetLabels()
+		if labels[InstallLabel] != "agent" {
+			t.Errorf("expected the object is labeled, but got %v", labels)
+		}
+	}
+	if mustToUnstructured(t, objs[1]).GetLabels()["app"] != "agent" {
+		t.Errorf("expected the existing labels are kept")
+	}
+}
+
+func TestInventoryPrune(t *testing.T) {
+	ctx := context.TODO()
+	inventory := &Inventory{Install: "agent", Namespace: "xcm"}
+
+	owned := newConfigMap("owned", map[string]string{InstallLabel: "agent"})
+	foreign := newConfigMap("foreign", nil)
+	other := newConfigMap("other", map[string]string{InstallLabel: "connector"})
+	current := inventory.Label(newNamespace("xcm"), newConfigMap("current", nil))
+
+	applier := &Applier{
+		KubeClient: fake.NewSimpleClientset(),
+		DynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+			mustToUnstructured(t, owned), mustToUnstructured(t, foreign), mustToUnstructured(t, other),
+			mustToUnstructured(t, current[1])),
+	}
+
+	// nothing is pruned without an inventory
+	pruned, err := inventory.Prune(ctx, &bytes.Buffer{}, applier, current...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pruned) != 0 {
+		t.Errorf("expected nothing is pruned, but got %v", pruned)
+	}
+
+	// the objects that are gone are ignored
+	gone := newConfigMap("gone", map[string]string{InstallLabel: "agent"})
+	if err := inventory.Save(ctx, applier, append(current, owned, foreign, other, gone)...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	pruned, err = inventory.Prune(ctx, out, applier, current...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pruned) != 1 || pruned[0].Name != "owned" {
+		t.Errorf("expected only the owned object is pruned, but got %v", pruned)
+	}
+	if out.String() != "configmap/owned pruned\n" {
+		t.Errorf("unexpected report %q", out.String())
+	}
+
+	gvr := GroupVersionResource(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	for name, expected := range map[string]bool{"owned": false, "foreign": true, "other": true, "current": true} {
+		_, err := applier.DynamicClient.Resource(gvr).Namespace("xcm").Get(ctx, name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err) && expected:
+			t.Errorf("expected the config map %s is kept", name)
+		case err == nil && !expected:
+			t.Errorf("expected the config map %s is pruned", name)
+		case err != nil && !apierrors.IsNotFound(err):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if err := inventory.Save(ctx, applier, current...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	refs, err := inventory.Load(ctx, applier)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(refs) != 2 || refs[0] != (ObjectRef{APIVersion: "v1", Kind: "Namespace", Name: "xcm"}) {
+		t.Errorf("unexpected inventory %v", refs)
+	}
+}
+
+func TestInventoryMerge(t *testing.T) {
+	ctx := context.TODO()
+	inventory := &Inventory{Install: "agent", Namespace: "xcm"}
+	applier := &Applier{KubeClient: fake.NewSimpleClientset()}
+
+	if err := inventory.Merge(ctx, applier, newNamespace("xcm"), newConfigMap("previous", nil)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := inventory.Merge(ctx, applier, newNamespace("xcm"), newConfigMap("current", nil)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	refs, err := inventory.Load(ctx, applier)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	// the objects that are no longer rendered are kept, so that they can be pruned later
+	if strings.Join(names, ",") != "xcm,previous,current" {
+		t.Errorf("unexpected inventory %v", refs)
+	}
+}
@@ -0,0 +1,156 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// FieldManager is the field manager of the objects that are applied by xcm.
+const FieldManager = "xcm-cli"
+
+// ConflictError is returned when the server-side apply of an object conflicts with the other field
+// managers, e.g. a field is changed by kubectl after the object is applied.
+type ConflictError struct {
+	// Object is the kind and the name of the object, e.g. 'deployment/multicluster-controlplane'.
+	Object string
+
+	// Conflicts are the conflicting fields and their managers.
+	Conflicts []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s conflicts with the other field managers: %s, apply it again with force to take the ownership",
+		e.Object, strings.Join(e.Conflicts, "; "))
+}
+
+// ServerSideApply applies the object with the server-side apply, a ConflictError is returned if the
+// object conflicts with the other field managers unless force is set.
+func ServerSideApply(ctx context.Context, dynamicClient dynamic.Interface, obj runtime.Object, force bool) error {
+	return serverSideApply(ctx, dynamicClient, obj, force, false)
+}
+
+// DryRunApply submits the object to the server with the server-side apply in the dry-run mode, so
+// that the object is validated by the server and its admission webhooks without being persisted.
+func DryRunApply(ctx context.Context, dynamicClient dynamic.Interface, obj runtime.Object, force bool) error {
+	return serverSideApply(ctx, dynamicClient, obj, force, true)
+}
+
+func serverSideApply(ctx context.Context, dynamicClient dynamic.Interface, obj runtime.Object, force, dryRun bool) error {
+	required, err := ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(required.Object)
+	if err != nil {
+		return err
+	}
+
+	opts := metav1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &force,
+	}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	_, err = ResourceInterface(dynamicClient, required).Patch(ctx, required.GetName(), types.ApplyPatchType, data, opts)
+	return conflictError(required, err)
+}
+
+// conflictError converts the conflict of the server-side apply to a ConflictError, the other errors
+// are returned as they are.
+func conflictError(obj *unstructured.Unstructured, err error) error {
+	if !errors.IsConflict(err) {
+		return err
+	}
+
+	status, ok := err.(errors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return err
+	}
+
+	conflicts := []string{}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type == metav1.CauseTypeFieldManagerConflict {
+			conflicts = append(conflicts, cause.Message)
+		}
+	}
+	if len(conflicts) == 0 {
+		return err
+	}
+
+	return &ConflictError{
+		Object:    fmt.Sprintf("%s/%s", strings.ToLower(obj.GetKind()), obj.GetName()),
+		Conflicts: conflicts,
+	}
+}
+
+// ToUnstructured converts the typed object to an unstructured object, the status and the empty
+// creation timestamps are removed, so that the object can be used as an apply configuration.
+func ToUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		return nil, fmt.Errorf("the kind of the object %T is unknown", obj)
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	required := &unstructured.Unstructured{Object: content}
+	unstructured.RemoveNestedField(required.Object, "status")
+	removeNullCreationTimestamps(required.Object)
+	return required, nil
+}
+
+// removeNullCreationTimestamps removes the null creation timestamps of the object and its embedded
+// objects, e.g. the pod template of a deployment.
+func removeNullCreationTimestamps(obj map[string]interface{}) {
+	for key, value := range obj {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if key == "metadata" {
+				if timestamp, ok := v["creationTimestamp"]; ok && timestamp == nil {
+					delete(v, "creationTimestamp")
+				}
+			}
+			removeNullCreationTimestamps(v)
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					removeNullCreationTimestamps(m)
+				}
+			}
+		}
+	}
+}
+
+// ResourceInterface returns the dynamic resource interface of the object, the resource is guessed
+// from the kind of the object.
+func ResourceInterface(dynamicClient dynamic.Interface, obj *unstructured.Unstructured) dynamic.ResourceInterface {
+	gvr := GroupVersionResource(obj.GroupVersionKind())
+	if obj.GetNamespace() == "" {
+		return dynamicClient.Resource(gvr)
+	}
+	return dynamicClient.Resource(gvr).Namespace(obj.GetNamespace())
+}
+
+// GroupVersionResource guesses the resource of the kind, e.g. the resource of the kind Deployment is
+// deployments.
+func GroupVersionResource(gvk schema.GroupVersionKind) schema.GroupVersionResource {
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	return gvr
+}
@@ -9,11 +9,14 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/yaml"
 )
 
 var (
@@ -25,6 +28,7 @@ var (
 func init() {
 	utilruntime.Must(appsv1.AddToScheme(genericScheme))
 	utilruntime.Must(corev1.AddToScheme(genericScheme))
+	utilruntime.Must(networkingv1.AddToScheme(genericScheme))
 	utilruntime.Must(rbacv1.AddToScheme(genericScheme))
 	utilruntime.Must(crdv1.AddToScheme(genericScheme))
 	utilruntime.Must(ocmoperatorv1.AddToScheme(genericScheme))
@@ -55,9 +59,28 @@ func MustCreateObjectFromTemplate(file string, tb []byte, config interface{}) ru
 	raw := MustRenderFromTemplate(file, tb, config)
 
 	obj, _, err := genericCodec.Decode(raw, nil, nil)
+	if runtime.IsNotRegisteredError(err) {
+		// the kinds that are not in the scheme, e.g. the OpenShift route, are decoded as unstructured
+		// objects
+		return mustCreateUnstructured(raw)
+	}
 	if err != nil {
 		panic(err)
 	}
 
 	return obj
 }
+
+func mustCreateUnstructured(raw []byte) runtime.Object {
+	data, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		panic(err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(data); err != nil {
+		panic(err)
+	}
+
+	return obj
+}
@@ -2,15 +2,30 @@ package rest
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
+	"sync"
 
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/constants"
+)
+
+const clustersPath = "/api/cluster_inventory_mgmt/v1/clusters"
+
+// The statuses of a cluster in the xCM inventory.
+const (
+	ClusterStatusAvailable   = "Available"
+	ClusterStatusUnavailable = "Unavailable"
+	ClusterStatusUnknown     = "Unknown"
 )
 
 type Cluster struct {
@@ -22,15 +37,60 @@ type Cluster struct {
 	Region   string `json:"region"`
 }
 
-func GetAllClusters(xCMServer string) ([]Cluster, error) {
-	url := fmt.Sprintf("%s/api/cluster_inventory_mgmt/v1/clusters", xCMServer)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// Client sends authenticated requests to the xCM API gateway. The bearer token is taken from the
+// given API configuration, and it is refreshed with the refresh token once it is expired.
+type Client struct {
+	lock       sync.Mutex
+	config     *configs.APIConfig
+	url        string
+	httpClient *http.Client
+}
+
+// NewClient creates a client with the given API configuration, the configuration must be armed,
+// otherwise a login is required.
+func NewClient(config *configs.APIConfig) (*Client, error) {
+	armed, reason, err := config.Armed()
+	if err != nil {
+		return nil, fmt.Errorf("cannot check the configuration: %v", err)
+	}
+	if !armed {
+		if reason == "" {
+			return nil, fmt.Errorf("login required")
+		}
+		return nil, fmt.Errorf("login required, %s", reason)
+	}
+
+	url, err := config.GatewayURL()
 	if err != nil {
 		return nil, err
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	return &Client{
+		config:     config,
+		url:        strings.TrimSuffix(url, "/"),
+		httpClient: &http.Client{Transport: NewTransport(config.Insecure)},
+	}, nil
+}
+
+// NewTransport returns the transport of the requests to the xCM API gateway and its OpenID server,
+// the TLS certificates and host names aren't verified if insecure is true.
+func NewTransport(insecure bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true, // #nosec G402
+		}
+	}
+	return transport
+}
+
+// URL returns the URL of the xCM API gateway.
+func (c *Client) URL() string {
+	return c.url
+}
+
+func (c *Client) GetAllClusters() ([]Cluster, error) {
+	resp, err := c.do(http.MethodGet, clustersPath, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -48,15 +108,8 @@ func GetAllClusters(xCMServer string) ([]Cluster, error) {
 	return clusters, nil
 }
 
-func GetCluster(xCMServer string, clusterID string) (*Cluster, error) {
-	url := fmt.Sprintf("%s/api/cluster_inventory_mgmt/v1/clusters/%s", xCMServer, clusterID)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func (c *Client) GetCluster(clusterID string) (*Cluster, error) {
+	resp, err := c.do(http.MethodGet, fmt.Sprintf("%s/%s", clustersPath, clusterID), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -75,21 +128,13 @@ func GetCluster(xCMServer string, clusterID string) (*Cluster, error) {
 	return cluster, nil
 }
 
-func CreateCluster(server string, managedCluster *clusterv1.ManagedCluster) error {
+func (c *Client) CreateCluster(managedCluster *clusterv1.ManagedCluster) error {
 	clusterData, err := json.Marshal(toCluster(managedCluster))
 	if err != nil {
 		return err
 	}
 
-	url := fmt.Sprintf("%s/api/cluster_inventory_mgmt/v1/clusters", server)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(clusterData))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(http.MethodPost, clustersPath, bytes.NewBuffer(clusterData))
 	if err != nil {
 		return err
 	}
@@ -103,27 +148,106 @@ func CreateCluster(server string, managedCluster *clusterv1.ManagedCluster) erro
 	return nil
 }
 
+// DeleteCluster deregisters the cluster from the xCM inventory, the cluster that is not found is ignored.
+func (c *Client) DeleteCluster(clusterID string) error {
+	resp, err := c.do(http.MethodDelete, fmt.Sprintf("%s/%s", clustersPath, clusterID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+
+	return fmt.Errorf("failed to delete cluster %s statuscode=%d, status=%s",
+		clusterID, resp.StatusCode, resp.Status)
+}
+
+// do sends a request to the given path of the xCM API gateway with the bearer token.
+func (c *Client) do(method, path string, body io.Reader) (*http.Response, error) {
+	token, err := c.accessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, c.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// accessToken returns an access token that hasn't expired. If the current access token is expired,
+// new tokens are requested with the refresh token and saved to the configuration file.
+func (c *Client) accessToken() (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	armed, reason, err := c.config.Armed()
+	if err != nil {
+		return "", fmt.Errorf("cannot check the configuration: %v", err)
+	}
+	if !armed {
+		return "", fmt.Errorf("login required, %s", reason)
+	}
+
+	usable, err := c.config.AccessTokenUsable()
+	if err != nil {
+		return "", fmt.Errorf("cannot check the access token: %v", err)
+	}
+	if usable {
+		return c.config.AccessToken, nil
+	}
+
+	connection, err := c.config.Connection()
+	if err != nil {
+		return "", fmt.Errorf("cannot create connection: %v", err)
+	}
+	defer connection.Close()
+
+	accessToken, refreshToken, err := connection.Tokens()
+	if err != nil {
+		return "", fmt.Errorf("cannot refresh token: %v", err)
+	}
+
+	c.config.AccessToken = accessToken
+	c.config.RefreshToken = refreshToken
+	if err := c.config.Save(); err != nil {
+		return "", fmt.Errorf("cannot save config file: %v", err)
+	}
+
+	return accessToken, nil
+}
+
 func toCluster(managedCluster *clusterv1.ManagedCluster) *Cluster {
 	id := strings.TrimPrefix(managedCluster.Name, "cluster-")
 
-	status := "Unknown"
+	status := ClusterStatusUnknown
 	available := meta.FindStatusCondition(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable)
 	if available != nil {
 		switch available.Status {
 		case metav1.ConditionTrue:
-			status = "Available"
+			status = ClusterStatusAvailable
 		case metav1.ConditionFalse:
-			status = "Unavailable"
+			status = ClusterStatusUnavailable
 		}
 	}
 
 	return &Cluster{
 		ID:       id,
 		Status:   status,
-		Type:     findClusterClaims(managedCluster.Status.ClusterClaims, "product.open-cluster-management.io"),
-		Version:  findClusterClaims(managedCluster.Status.ClusterClaims, "kubeversion.open-cluster-management.io"),
-		Platform: findClusterClaims(managedCluster.Status.ClusterClaims, "platform.open-cluster-management.io"),
-		Region:   findClusterClaims(managedCluster.Status.ClusterClaims, "region.open-cluster-management.io"),
+		Type:     findClusterClaims(managedCluster.Status.ClusterClaims, constants.ProductClaimName),
+		Version:  findClusterClaims(managedCluster.Status.ClusterClaims, constants.KubeVersionClaimName),
+		Platform: findClusterClaims(managedCluster.Status.ClusterClaims, constants.PlatformClaimName),
+		Region:   findClusterClaims(managedCluster.Status.ClusterClaims, constants.RegionClaimName),
 	}
 }
 
@@ -1,19 +1,181 @@
 package rest
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/skeeey/xcm-cli/pkg/configs"
+	"github.com/skeeey/xcm-cli/pkg/credstore"
 )
 
-func TestXxx(t *testing.T) {
+func newToken(t *testing.T, typ string, expiresIn time.Duration) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ": typ,
+		"exp": time.Now().Add(expiresIn).Unix(),
+	}).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func newGateway(t *testing.T, accessToken string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+accessToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == clustersPath:
+			_ = json.NewEncoder(w).Encode([]Cluster{{ID: "c1", Status: "Available"}})
+		case r.Method == http.MethodGet && r.URL.Path == clustersPath+"/c1":
+			_ = json.NewEncoder(w).Encode(Cluster{ID: "c1", Status: "Available"})
+		case r.Method == http.MethodPost && r.URL.Path == clustersPath:
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodDelete && r.URL.Path == clustersPath+"/c1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
 
-	if err := CreateCluster("http://3.137.154.243", &clusterv1.ManagedCluster{
+func TestClient(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(credstore.BackendEnv, credstore.BackendFile)
+
+	accessToken := newToken(t, "Bearer", time.Hour)
+	gateway := newGateway(t, accessToken)
+	defer gateway.Close()
+
+	client, err := NewClient(&configs.APIConfig{
+		AccessToken: accessToken,
+		URL:         gateway.URL,
+		TokenURL:    gateway.URL + "/token",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clusters, err := client.GetAllClusters()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(clusters) != 1 || clusters[0].ID != "c1" {
+		t.Errorf("unexpected clusters: %v", clusters)
+	}
+
+	cluster, err := client.GetCluster("c1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster.Status != "Available" {
+		t.Errorf("unexpected cluster: %v", cluster)
+	}
+
+	if err := client.CreateCluster(&clusterv1.ManagedCluster{
 		ObjectMeta: v1.ObjectMeta{
-			Name: "spoke-test",
+			Name: "cluster-c2",
 		},
 	}); err != nil {
-		t.Errorf("%v", err)
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := client.DeleteCluster("c1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := client.DeleteCluster("c2"); err != nil {
+		t.Errorf("expected the cluster that is not found is ignored, but got %v", err)
+	}
+}
+
+func TestClientRefreshToken(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(credstore.BackendEnv, credstore.BackendFile)
+
+	accessToken := newToken(t, "Bearer", time.Hour)
+	refreshToken := newToken(t, "Refresh", 10*time.Hour)
+	gateway := newGateway(t, accessToken)
+	defer gateway.Close()
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != refreshToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  accessToken,
+			"refresh_token": refreshToken,
+			"token_type":    "Bearer",
+		})
+	}))
+	defer tokenServer.Close()
+
+	config := &configs.APIConfig{
+		AccessToken:  newToken(t, "Bearer", -time.Hour),
+		RefreshToken: refreshToken,
+		URL:          gateway.URL,
+		TokenURL:     tokenServer.URL,
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := client.GetAllClusters(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.AccessToken != accessToken {
+		t.Errorf("expected the access token is refreshed")
+	}
+
+	saved, err := configs.LoadAPIConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if saved.AccessToken != accessToken {
+		t.Errorf("expected the refreshed access token is saved")
+	}
+}
+
+func TestClientLoginRequired(t *testing.T) {
+	_, err := NewClient(&configs.APIConfig{
+		AccessToken: newToken(t, "Bearer", -time.Hour),
+		URL:         "https://localhost",
+		TokenURL:    "https://localhost/token",
+	})
+	if err == nil {
+		t.Errorf("expected login required error")
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	if _, err := (&http.Client{Transport: NewTransport(false)}).Get(server.URL); err == nil {
+		t.Errorf("expected the self-signed certificate is rejected")
+	}
+
+	resp, err := (&http.Client{Transport: NewTransport(true)}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
+	resp.Body.Close()
 }